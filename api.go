@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/listing"
+	"github.com/demmydemon/abventure/parser"
+	"github.com/demmydemon/abventure/render"
+	"github.com/demmydemon/abventure/signing"
+	"github.com/go-chi/chi/v5"
+)
+
+const apiBase = "/api/v1/"
+
+type apiError struct {
+	Error string
+}
+
+type apiListing struct {
+	Name     string
+	Title    string
+	Href     string
+	Metadata parser.Metadata
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, format string, a ...any) {
+	writeJSON(w, status, apiError{Error: fmt.Sprintf(format, a...)})
+}
+
+// apiRoutes sets up the JSON play API on r, which is expected to be mounted at apiBase.
+func apiRoutes(r chi.Router, idx *listing.Index, signer *signing.Signer) {
+	r.Get("/abventures", func(w http.ResponseWriter, r *http.Request) {
+		apiAbventures(w, idx)
+	})
+	r.Get("/{abventure:[a-zA-Z0-9_-]+}/", func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "abventure")
+		apiScene(w, idx, signer, name, "", 0, inventory.State{})
+	})
+	r.Get("/{abventure:[a-zA-Z0-9_-]+}/{token:[a-f0-9]{8}[a-zA-Z0-9._~-]*}", func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "abventure")
+		cell, version, state, err := signer.ParseToken(name, chi.URLParam(r, "token"))
+		if errors.Is(err, signing.ErrBadSignature) {
+			writeAPIError(w, http.StatusForbidden, "%s", err)
+			return
+		}
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		apiScene(w, idx, signer, name, cell, version, state)
+	})
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusNotFound, "no such endpoint")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	})
+}
+
+func apiAbventures(w http.ResponseWriter, idx *listing.Index) {
+	out := []apiListing{}
+	for _, name := range idx.Names() {
+		lst, exist := idx.Get(name)
+		if !exist {
+			continue // Removed since we got the names
+		}
+		out = append(out, apiListing{
+			Name:     name,
+			Title:    lst.Title(),
+			Href:     apiBase + name + "/",
+			Metadata: lst.Metadata(),
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func apiScene(w http.ResponseWriter, idx *listing.Index, signer *signing.Signer, name string, cell string, version int, state inventory.State) {
+	lst, exist := idx.Get(name)
+	if !exist {
+		writeAPIError(w, http.StatusNotFound, "no such abventure %s", name)
+		return
+	}
+	abv, published, err := lst.GetAbventure()
+	if err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "abventure %s fails to parse, and can't be played right now", name)
+		return
+	}
+	state, err = published.Migrate(version, state, abv.Inventory)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	scene, err := abv.Evaluate(cell, state)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, "%s", err)
+		return
+	}
+
+	current := published.Current(abv.Inventory)
+	renderer := render.JSON{
+		Link: func(cellHash string, state inventory.State) string {
+			return apiBase + name + "/" + signer.Token(name, cellHash, current, state)
+		},
+		Token: func(cellHash string, state inventory.State) string {
+			return signer.Token(name, cellHash, current, state)
+		},
+	}
+	writeJSON(w, http.StatusOK, renderer.Scene(scene))
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/demmydemon/abventure/listing"
+	"github.com/demmydemon/abventure/render"
+	"github.com/demmydemon/abventure/signing"
+	"github.com/go-chi/chi/v5"
+)
+
+const apiSource = `API
+%Torch A torch.
+:Start Beginning
+&Torch You find a torch.
+>Next Onwards
+:Next Further
+?Torch You have a torch.
+`
+
+// apiServer sets up the API as main does, with a working abventure called story and a broken one called broken.
+func apiServer(t *testing.T) (http.Handler, *signing.Signer) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "story.abv"), []byte(apiSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.abv"), []byte("Broken\n:Start\n>One >Two\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	idx := listing.NewIndex(dir + "/")
+	signer := signing.New("secret")
+	r := chi.NewRouter()
+	r.Route("/api/v1", func(r chi.Router) {
+		apiRoutes(r, idx, signer)
+	})
+	return r, signer
+}
+
+// apiGet makes a request, checks that it gives the expected status and JSON, and decodes the body into out.
+func apiGet(t *testing.T, handler http.Handler, method string, path string, status int, out any) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	if w.Code != status {
+		t.Errorf("%s %s: expected status %d, got %d: %s", method, path, status, w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("%s %s: expected JSON, got %s", method, path, contentType)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+		t.Errorf("%s %s: bad JSON: %s: %s", method, path, err, w.Body.String())
+	}
+}
+
+func TestAPIPlay(t *testing.T) {
+	handler, _ := apiServer(t)
+
+	var listings []apiListing
+	apiGet(t, handler, "GET", "/api/v1/abventures", http.StatusOK, &listings)
+	if len(listings) != 2 || listings[1].Name != "story" || listings[1].Title != "API" || listings[1].Href != "/api/v1/story/" {
+		t.Errorf("Wrong abventure list: %+v", listings)
+	}
+
+	var start render.JSONScene
+	apiGet(t, handler, "GET", "/api/v1/story/", http.StatusOK, &start)
+	if start.Cell != "Start" || len(start.Choices) != 1 || start.Choices[0].Cell != "Next" {
+		t.Fatalf("Wrong starting scene: %+v", start)
+	}
+	choice := start.Choices[0]
+	if choice.Href != "/api/v1/story/"+choice.Token || !strings.Contains(choice.Token, "~") {
+		t.Errorf("Choice should link to its signed token: %+v", choice)
+	}
+
+	var next render.JSONScene
+	apiGet(t, handler, "GET", choice.Href, http.StatusOK, &next)
+	if next.Cell != "Next" || !reflect.DeepEqual(next.Inventory, []string{"A torch."}) {
+		t.Errorf("Following the choice should bring the torch along to Next: %+v", next)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	handler, signer := apiServer(t)
+	var start render.JSONScene
+	apiGet(t, handler, "GET", "/api/v1/story/", http.StatusOK, &start)
+	if len(start.Choices) != 1 {
+		t.Fatalf("Wrong starting scene: %+v", start)
+	}
+	token, _, _ := strings.Cut(start.Choices[0].Token, "~")
+	tampered := token + "~" + signing.New("wrong secret").Sign("story", token[:8], token[8:])
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		error  string
+	}{
+		{"GET", "/api/v1/nowhere/", http.StatusNotFound, "no such abventure nowhere"},
+		{"GET", "/api/v1/story/" + tampered, http.StatusForbidden, signing.ErrBadSignature.Error()},
+		{"GET", "/api/v1/story/" + start.Hash + "x~" + signer.Sign("story", start.Hash, "x"), http.StatusBadRequest, signing.ErrBadToken.Error()},
+		{"GET", "/api/v1/story/00000000~" + signer.Sign("story", "00000000", ""), http.StatusNotFound, "00000000"},
+		{"GET", "/api/v1/broken/", http.StatusServiceUnavailable, "abventure broken fails to parse"},
+		{"GET", "/api/v1/no/such/endpoint", http.StatusNotFound, "no such endpoint"},
+		{"POST", "/api/v1/abventures", http.StatusMethodNotAllowed, "method POST not allowed"},
+	}
+	for _, test := range tests {
+		var out apiError
+		apiGet(t, handler, test.method, test.path, test.status, &out)
+		if !strings.Contains(out.Error, test.error) {
+			t.Errorf("%s %s: expected an error with %q, got %q", test.method, test.path, test.error, out.Error)
+		}
+	}
+}
+
+func TestAPIRearranged(t *testing.T) {
+	dir := t.TempDir()
+	story := filepath.Join(dir, "story.abv")
+	if err := os.WriteFile(story, []byte(apiSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	idx := listing.NewIndex(dir + "/")
+	r := chi.NewRouter()
+	r.Route("/api/v1", func(r chi.Router) {
+		apiRoutes(r, idx, signing.New("secret"))
+	})
+
+	var start render.JSONScene
+	apiGet(t, r, "GET", "/api/v1/story/", http.StatusOK, &start)
+	if len(start.Choices) != 1 {
+		t.Fatalf("Wrong starting scene: %+v", start)
+	}
+
+	// A new item before the torch takes its slot, without anyone publishing the change.
+	rearranged := strings.Replace(apiSource, "%Torch", "%Map A map.\n%Torch", 1)
+	if err := os.WriteFile(story, []byte(rearranged), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(story, later, later); err != nil {
+		t.Fatal(err)
+	}
+	idx.Refresh()
+
+	var next render.JSONScene
+	apiGet(t, r, "GET", start.Choices[0].Href, http.StatusOK, &next)
+	if !reflect.DeepEqual(next.Inventory, []string{"A torch."}) {
+		t.Errorf("A link from before the change should still bring the torch along: %+v", next)
+	}
+}
@@ -0,0 +1,148 @@
+// Package bitset implements a set of bits that grows as needed, used to store inventory state.
+package bitset
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/bits"
+)
+
+// Set is a set of non-negative integers, stored as bits. The zero value is an empty set, ready to use.
+type Set struct {
+	words []uint64
+}
+
+// FromUint64 creates a set holding the bits of n, as used by the old single-number inventory state.
+func FromUint64(n uint64) Set {
+	set := Set{}
+	if n != 0 {
+		set.words = []uint64{n}
+	}
+	return set
+}
+
+// Has returns true if the given bit is in the set.
+func (set Set) Has(bit int) bool {
+	word := bit / 64
+	if bit < 0 || word >= len(set.words) {
+		return false
+	}
+	return set.words[word]&(1<<(bit%64)) != 0
+}
+
+// Add puts the given bit in the set, growing it if needed.
+func (set *Set) Add(bit int) {
+	if bit < 0 {
+		return
+	}
+	word := bit / 64
+	for len(set.words) <= word {
+		set.words = append(set.words, 0)
+	}
+	set.words[word] |= 1 << (bit % 64)
+}
+
+// Remove takes the given bit out of the set.
+func (set *Set) Remove(bit int) {
+	word := bit / 64
+	if bit < 0 || word >= len(set.words) {
+		return
+	}
+	set.words[word] &^= 1 << (bit % 64)
+	set.trim()
+}
+
+// trim drops any trailing empty words, so that equal sets always have equal representations.
+func (set *Set) trim() {
+	for len(set.words) > 0 && set.words[len(set.words)-1] == 0 {
+		set.words = set.words[:len(set.words)-1]
+	}
+}
+
+// Count returns how many bits are in the set.
+func (set Set) Count() int {
+	count := 0
+	for _, word := range set.words {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+// IsEmpty returns true if there are no bits in the set.
+func (set Set) IsEmpty() bool {
+	return len(set.words) == 0
+}
+
+// Clone returns a copy of the set that can be changed without affecting the original.
+func (set Set) Clone() Set {
+	if len(set.words) == 0 {
+		return Set{}
+	}
+	words := make([]uint64, len(set.words))
+	copy(words, set.words)
+	return Set{words: words}
+}
+
+// Equal returns true if both sets hold exactly the same bits.
+func (set Set) Equal(other Set) bool {
+	if len(set.words) != len(other.words) {
+		return false
+	}
+	for i, word := range set.words {
+		if other.words[i] != word {
+			return false
+		}
+	}
+	return true
+}
+
+// Bits returns every bit in the set, in ascending order.
+func (set Set) Bits() []int {
+	out := make([]int, 0, set.Count())
+	for i, word := range set.words {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			out = append(out, i*64+bit)
+			word &^= 1 << bit
+		}
+	}
+	return out
+}
+
+// Bytes returns the set as little-endian bytes, without any trailing zero bytes.
+func (set Set) Bytes() []byte {
+	out := make([]byte, 0, len(set.words)*8)
+	for _, word := range set.words {
+		for i := 0; i < 8; i++ {
+			out = append(out, byte(word>>(i*8)))
+		}
+	}
+	for len(out) > 0 && out[len(out)-1] == 0 {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// FromBytes creates a set from little-endian bytes, as returned by Bytes.
+func FromBytes(data []byte) Set {
+	set := Set{words: make([]uint64, (len(data)+7)/8)}
+	for i, b := range data {
+		set.words[i/8] |= uint64(b) << ((i % 8) * 8)
+	}
+	set.trim()
+	return set
+}
+
+// String returns the set in a compact, URL-safe encoding. The empty set is an empty string.
+func (set Set) String() string {
+	return base64.RawURLEncoding.EncodeToString(set.Bytes())
+}
+
+// Parse decodes a set from the encoding returned by String.
+func Parse(encoded string) (Set, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Set{}, fmt.Errorf("decoding bit set: %w", err)
+	}
+	return FromBytes(data), nil
+}
@@ -0,0 +1,68 @@
+package bitset_test
+
+import (
+	"testing"
+
+	"github.com/demmydemon/abventure/bitset"
+)
+
+func TestAddRemove(t *testing.T) {
+	set := bitset.Set{}
+	set.Add(0)
+	set.Add(70)
+	set.Add(300)
+	if !set.Has(0) || !set.Has(70) || !set.Has(300) {
+		t.Errorf("Set is missing added bits: %v", set.Bits())
+	}
+	if set.Has(1) || set.Has(64) || set.Has(1000) {
+		t.Errorf("Set has bits that were never added: %v", set.Bits())
+	}
+	if set.Count() != 3 {
+		t.Errorf("Set has wrong count: Expected 3, got %d", set.Count())
+	}
+
+	set.Remove(300)
+	set.Remove(70)
+	if !set.Equal(bitset.FromUint64(1)) {
+		t.Errorf("Set did not shrink back after removing bits: %v", set.Bits())
+	}
+}
+
+func TestClone(t *testing.T) {
+	original := bitset.FromUint64(5)
+	clone := original.Clone()
+	clone.Add(1)
+	if original.Has(1) {
+		t.Error("Changing a clone changed the original")
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	known := map[string][]int{
+		"":             {},
+		"AQ":           {0},
+		"Aw":           {0, 1},
+		"AAAAAAAAAAAB": {64},
+	}
+	for encoded, members := range known {
+		set := bitset.Set{}
+		for _, bit := range members {
+			set.Add(bit)
+		}
+		if set.String() != encoded {
+			t.Errorf("Set %v encoded wrong: Expected %q, got %q", members, encoded, set.String())
+		}
+		parsed, err := bitset.Parse(encoded)
+		if err != nil {
+			t.Errorf("Parsing %q failed: %s", encoded, err)
+			continue
+		}
+		if !parsed.Equal(set) {
+			t.Errorf("Parsing %q gave wrong set: Expected %v, got %v", encoded, members, parsed.Bits())
+		}
+	}
+
+	if _, err := bitset.Parse("not*valid"); err == nil {
+		t.Error("Parsing garbage did not return an error")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/demmydemon/abventure/explore"
+	"github.com/demmydemon/abventure/hash"
+	"github.com/demmydemon/abventure/parser"
+)
+
+// runExplore walks every reachable state of each file given, returning 1 if anything is unreachable or locked.
+func runExplore(args []string) int {
+	flags := flag.NewFlagSet("explore", flag.ExitOnError)
+	limit := flags.Int("limit", explore.DefaultLimit, "maximum number of states to look at")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: abv explore [-limit n] <file.abv>...")
+		return 2
+	}
+
+	status := 0
+	for _, filename := range flags.Args() {
+		abv, err := parser.ParseFile(filename, false)
+		if err != nil {
+			parser.PrintError(os.Stdout, err)
+			status = 1
+			continue
+		}
+
+		report := explore.Explore(&abv, *limit)
+		fmt.Printf("%s: %d reachable states\n", filename, report.States)
+		if report.Truncated {
+			fmt.Printf("%s: warning: gave up after %d states, the rest of this report is incomplete\n", filename, *limit)
+		}
+		for _, cell := range report.UnreachableCells {
+			fmt.Printf("%s: cell %s can never be reached\n", abv.Cells[hash.Single(cell)].Pos, cell)
+		}
+		for _, line := range report.UnreachableLines {
+			if line.Text == "" {
+				fmt.Printf("%s: [%s] line can never be reached\n", line.Pos, line.Cell)
+				continue
+			}
+			fmt.Printf("%s: [%s] line can never be reached: %q\n", line.Pos, line.Cell, line.Text)
+		}
+		for _, item := range report.UnobtainableItems {
+			fmt.Printf("%s: item %s can never be obtained\n", filename, item)
+		}
+		for _, lock := range report.SoftLocks {
+			holding := "nothing"
+			if len(lock.Items) > 0 {
+				holding = strings.Join(lock.Items, ", ")
+			}
+			fmt.Printf("%s: [%s] no way out when holding %s\n", filename, lock.Cell, holding)
+		}
+		if !report.Clean() {
+			status = 1
+		}
+	}
+	return status
+}
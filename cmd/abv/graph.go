@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/demmydemon/abventure/graph"
+	"github.com/demmydemon/abventure/parser"
+)
+
+// runGraph writes the structure of an abventure as a DOT or Mermaid graph.
+func runGraph(args []string) int {
+	flags := flag.NewFlagSet("graph", flag.ExitOnError)
+	format := flags.String("format", "dot", "output format, dot or mermaid")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: abv graph [-format dot|mermaid] <file.abv>")
+		return 2
+	}
+
+	abv, err := parser.ParseFile(flags.Arg(0), false)
+	if err != nil {
+		parser.PrintError(os.Stderr, err)
+		return 1
+	}
+
+	switch *format {
+	case "dot":
+		err = graph.DOT(os.Stdout, &abv)
+	case "mermaid":
+		err = graph.Mermaid(os.Stdout, &abv)
+	default:
+		fmt.Fprintf(os.Stderr, "abv graph: unknown format %q\n", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/demmydemon/abventure/lint"
+	"github.com/demmydemon/abventure/manifest"
+	"github.com/demmydemon/abventure/parser"
+)
+
+// runLint parses and checks each file given, returning 1 if any of them has errors.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	strict := flags.Bool("strict", false, "treat warnings as errors")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: abv lint [-strict] <file.abv>...")
+		return 2
+	}
+
+	status := 0
+	for _, filename := range flags.Args() {
+		abv, err := parser.ParseFile(filename, false)
+		if err != nil {
+			status = 1
+			var list parser.ErrorList
+			if !errors.As(err, &list) {
+				fmt.Printf("%s: error: %s\n", filename, err)
+				continue
+			}
+			// The parser still hands back what it could make sense of, so check that too.
+			parser.PrintError(os.Stdout, err)
+		}
+		diags := lint.Check(&abv)
+		published, err := manifest.Load(filename)
+		if err != nil {
+			fmt.Printf("%s: warning: %s\n", filename, err)
+		} else {
+			diags = append(diags, lint.CheckSlots(&abv, published)...)
+		}
+		for _, diag := range diags {
+			fmt.Println(diag)
+		}
+		if lint.HasErrors(diags) || (*strict && len(diags) > 0) {
+			status = 1
+		}
+	}
+	return status
+}
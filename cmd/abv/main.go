@@ -0,0 +1,49 @@
+// Command abv is a set of tools for abventure authors.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) int
+}
+
+var commands = []command{
+	{"lint", "lint <file.abv>...", "check abventure files for problems", runLint},
+	{"play", "play <file.abv>", "play an abventure in the terminal", runPlay},
+	{"test", "test [directory]", "run the *.abvtest walkthroughs next to the abventures", runTest},
+	{"explore", "explore [-limit n] <file.abv>...", "find unreachable cells, lines and items, and dead ends", runExplore},
+	{"solve", "solve <file.abv> <cell> [item]...", "find the shortest way to a cell, holding the given items", runSolve},
+	{"graph", "graph [-format f] <file.abv>", "draw the cells and links as a DOT or Mermaid graph", runGraph},
+	{"publish", "publish <file.abv>...", "record the item slots of a new version in the .lock file", runPublish},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: abv <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-30s %s\n", cmd.usage, cmd.summary)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	for _, cmd := range commands {
+		if cmd.name == name {
+			os.Exit(cmd.run(os.Args[2:]))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "abv: unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
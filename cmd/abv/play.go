@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/parser"
+	"github.com/demmydemon/abventure/render"
+)
+
+// runPlay lets you play through an abventure file in the terminal.
+func runPlay(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: abv play <file.abv>")
+		return 2
+	}
+	abv, err := parser.ParseFile(args[0], false)
+	if err != nil {
+		parser.PrintError(os.Stderr, err)
+		return 1
+	}
+	if err := play(&abv, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// play runs the game loop, reading choices from in and writing the cells to out, until quit or out of input.
+func play(abv *parser.Abventure, in io.Reader, out io.Writer) error {
+	input := bufio.NewScanner(in)
+	cell := ""
+	state := inventory.State{}
+
+	fmt.Fprintf(out, "%s\n%s\n", abv.Title, strings.Repeat("=", len(abv.Title)))
+
+	for {
+		scene, err := abv.Evaluate(cell, state)
+		if err != nil {
+			return err
+		}
+		if err := (render.Text{}).Render(out, scene); err != nil {
+			return err
+		}
+		choices := scene.Choices()
+
+		var choice *parser.Choice
+		for choice == nil {
+			fmt.Fprint(out, "\n> ")
+			if !input.Scan() {
+				fmt.Fprintln(out)
+				return input.Err()
+			}
+			answer := strings.TrimSpace(input.Text())
+			switch answer {
+			case "q", "quit":
+				return nil
+			case "r", "restart":
+				choice = &parser.Choice{}
+				continue
+			case "?", "h", "help":
+				fmt.Fprintln(out, "Type the number of a choice to take it, r to restart or q to quit.")
+				continue
+			}
+			number, err := strconv.Atoi(answer)
+			if err != nil || number < 1 || number > len(choices) {
+				fmt.Fprintf(out, "Pick a choice from 1 to %d, or ? for help.\n", len(choices))
+				continue
+			}
+			if choices[number-1].Broken {
+				fmt.Fprintln(out, "That path leads nowhere. The link is broken.")
+				continue
+			}
+			choice = choices[number-1]
+		}
+		cell, state = choice.Target, choice.State
+	}
+}
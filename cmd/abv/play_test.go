@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/parser"
+)
+
+const playSource = `Play
+%Torch A torch.
+:Start Outside
+You stand outside a cave.
+&Torch You find a torch.
+>Cave Enter the cave
+>Nowhere Dig a hole
+:Cave
+?Torch It is bright in here.
+!Torch It is too dark to see.
+>Start Leave
+`
+
+// playScript plays through the test abventure with the given input, returning what was written.
+func playScript(t *testing.T, input string) string {
+	t.Helper()
+	abv, err := parser.Parse(strings.NewReader(playSource), "play.abv", false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	out := bytes.Buffer{}
+	if err := play(&abv, strings.NewReader(input), &out); err != nil {
+		t.Fatalf("Unexpected play error: %s", err)
+	}
+	return out.String()
+}
+
+func TestPlayChoice(t *testing.T) {
+	out := playScript(t, "1\n")
+	expected := []string{
+		"Play\n====\n",
+		"## Outside\n\nYou stand outside a cave.\nYou find a torch.\n  [1] Enter the cave\n  [2] Dig a hole (broken)\n",
+		"## Cave\n\nIt is bright in here.\n  [1] Leave\n\nYou are holding:\n  - A torch.\n",
+	}
+	for _, want := range expected {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "too dark") {
+		t.Errorf("The torch should be carried into the cave:\n%s", out)
+	}
+
+	out = playScript(t, "1\n1\n")
+	if strings.Count(out, "## Outside") != 2 || strings.Count(out, "You find a torch.") != 1 {
+		t.Errorf("Going back out with the torch should not find it again:\n%s", out)
+	}
+}
+
+func TestPlayBadInput(t *testing.T) {
+	out := playScript(t, "x\n0\n3\n2\n?\nq\n1\n")
+	if count := strings.Count(out, "Pick a choice from 1 to 2, or ? for help."); count != 3 {
+		t.Errorf("Expected 3 complaints about bad choices, got %d:\n%s", count, out)
+	}
+	if !strings.Contains(out, "That path leads nowhere. The link is broken.") {
+		t.Errorf("Broken link should not be followed:\n%s", out)
+	}
+	if !strings.Contains(out, "Type the number of a choice") {
+		t.Errorf("Help is missing:\n%s", out)
+	}
+	if strings.Contains(out, "## Cave") {
+		t.Errorf("Input after quitting should be ignored:\n%s", out)
+	}
+}
+
+func TestPlayRestartAndEOF(t *testing.T) {
+	out := playScript(t, "1\nr\n")
+	if strings.Count(out, "## Outside") != 2 || strings.Count(out, "You find a torch.") != 2 {
+		t.Errorf("Restarting should start over with nothing in the inventory:\n%s", out)
+	}
+	if !strings.HasSuffix(out, "\n> \n") {
+		t.Errorf("Running out of input should end at the prompt:\n%s", out)
+	}
+
+	if out := playScript(t, ""); !strings.Contains(out, "## Outside") || !strings.HasSuffix(out, "\n> \n") {
+		t.Errorf("Empty input should show the start and stop:\n%s", out)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/demmydemon/abventure/lint"
+	"github.com/demmydemon/abventure/manifest"
+	"github.com/demmydemon/abventure/parser"
+)
+
+// runPublish records the current item and variable slots of each file given as a new version in its manifest.
+func runPublish(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: abv publish <file.abv>...")
+		return 2
+	}
+
+	status := 0
+	for _, filename := range args {
+		abv, err := parser.ParseFile(filename, false)
+		if err != nil {
+			parser.PrintError(os.Stderr, err)
+			fmt.Fprintf(os.Stderr, "%s: not published, fix the errors first\n", filename)
+			status = 1
+			continue
+		}
+		published, err := manifest.Load(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		for _, diag := range lint.CheckSlots(&abv, published) {
+			fmt.Println(diag)
+		}
+		version, isNew := published.Publish(abv.Inventory, time.Now())
+		if !isNew {
+			fmt.Printf("%s: version %d is still current\n", filename, version.Version)
+			continue
+		}
+		if err := published.Save(filename); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		fmt.Printf("%s: published version %d to %s\n", filename, version.Version, manifest.PathFor(filename))
+	}
+	return status
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/demmydemon/abventure/explore"
+	"github.com/demmydemon/abventure/hash"
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/manifest"
+	"github.com/demmydemon/abventure/parser"
+	"github.com/demmydemon/abventure/signing"
+)
+
+// runSolve finds the shortest way from Start to a cell, optionally holding some items.
+// Play URLs are signed with ABVSECRET, if set, so they work against a server using the same secret.
+func runSolve(args []string) int {
+	flags := flag.NewFlagSet("solve", flag.ExitOnError)
+	limit := flags.Int("limit", explore.DefaultLimit, "maximum number of states to look at")
+	flags.Parse(args)
+
+	if flags.NArg() < 2 {
+		fmt.Fprintln(os.Stderr, "Usage: abv solve [-limit n] <file.abv> <cell> [item]...")
+		return 2
+	}
+	filename, cell, holding := flags.Arg(0), flags.Arg(1), flags.Args()[2:]
+
+	abv, err := parser.ParseFile(filename, false)
+	if err != nil {
+		parser.PrintError(os.Stderr, err)
+		return 1
+	}
+
+	published, err := manifest.Load(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", filename, err)
+		return 1
+	}
+	version, isNew, err := published.Record(filename, abv.Inventory, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", filename, err)
+		return 1
+	}
+	if isNew {
+		fmt.Fprintf(os.Stderr, "%s: recorded the slots as version %d in %s\n", filename, version.Version, manifest.PathFor(filename))
+	}
+
+	steps, err := explore.Solve(&abv, cell, holding, *limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", filename, err)
+		return 1
+	}
+
+	name := strings.TrimSuffix(filepath.Base(filename), ".abv")
+	signer := signing.New(os.Getenv("ABVSECRET"))
+	fmt.Printf("%d choices from Start to %s\n", len(steps), cell)
+	fmt.Printf("     /%s/%s\n", name, signer.Token(name, hash.PrecalcStart, version.Version, inventory.State{}))
+	for num, step := range steps {
+		fmt.Printf("%3d. [%s] %s\n", num+1, step.Cell, step.Choice)
+		fmt.Printf("     /%s/%s\n", name, signer.Token(name, step.To.Cell, version.Version, step.To.Inventory))
+	}
+	return 0
+}
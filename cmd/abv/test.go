@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/demmydemon/abventure/parser"
+	"github.com/demmydemon/abventure/walkthrough"
+)
+
+// runTest runs every walkthrough script in the given directory against the abventure next to it.
+func runTest(args []string) int {
+	if len(args) > 1 {
+		fmt.Fprintln(os.Stderr, "Usage: abv test [directory]")
+		return 2
+	}
+	dir := "abventures"
+	if len(args) == 1 {
+		dir = args[0]
+	}
+
+	scripts, err := filepath.Glob(filepath.Join(dir, "*.abvtest"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(scripts) == 0 {
+		fmt.Printf("no walkthroughs in %s\n", dir)
+		return 0
+	}
+
+	failed := 0
+	for _, scriptFile := range scripts {
+		if err := runWalkthrough(scriptFile); err != nil {
+			fmt.Printf("FAIL %s\n", scriptFile)
+			parser.PrintError(os.Stdout, err)
+			failed++
+			continue
+		}
+		fmt.Printf("ok   %s\n", scriptFile)
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d of %d walkthroughs failed\n", failed, len(scripts))
+		return 1
+	}
+	return 0
+}
+
+func runWalkthrough(scriptFile string) error {
+	script, err := walkthrough.ParseFile(scriptFile)
+	if err != nil {
+		return err
+	}
+	abv, err := parser.ParseFile(strings.TrimSuffix(scriptFile, ".abvtest")+".abv", false)
+	if err != nil {
+		return err
+	}
+	return script.Run(&abv)
+}
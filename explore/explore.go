@@ -0,0 +1,179 @@
+// Package explore walks through every state an abventure can be in, to find the places a player can never get to,
+// and the places a player can never get out of.
+package explore
+
+import (
+	"sort"
+
+	"github.com/demmydemon/abventure/bitset"
+	"github.com/demmydemon/abventure/hash"
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/parser"
+)
+
+// DefaultLimit is how many states Explore looks at before giving up, unless told otherwise.
+const DefaultLimit = 100000
+
+// State is a place a player can be: a cell, and what they are holding when they get there.
+type State struct {
+	Cell      string // Hash of the cell
+	Inventory inventory.State
+}
+
+func (state State) key() string {
+	return state.Cell + "/" + inventory.FormatState(state.Inventory)
+}
+
+// Line is a line of an abventure that a player can never see or trigger.
+type Line struct {
+	Cell string
+	Pos  parser.Pos
+	Text string
+}
+
+// Lock is a state with no working way out of it.
+type Lock struct {
+	Cell  string   // Name of the cell
+	Items []string // Names of the items held
+}
+
+// Report is everything Explore found out about an abventure.
+type Report struct {
+	States            int      // How many (cell, inventory) states can be reached from the start
+	Truncated         bool     // True if the limit was hit, meaning the rest of the report is incomplete
+	UnreachableCells  []string // Names of the cells that can never be reached, in definition order
+	UnreachableLines  []Line   // Lines in reachable cells that never pass their checks
+	UnobtainableItems []string // Names of the items that can never be held
+	SoftLocks         []Lock   // States with no way out but broken links, or none at all
+}
+
+// Clean returns true if nothing is unreachable, unobtainable or locked.
+func (report *Report) Clean() bool {
+	return len(report.UnreachableCells) == 0 && len(report.UnreachableLines) == 0 &&
+		len(report.UnobtainableItems) == 0 && len(report.SoftLocks) == 0
+}
+
+// Explore does a breadth-first walk of every state reachable from the Start cell with an empty inventory,
+// looking at no more than limit states. If limit is zero or less, DefaultLimit is used.
+func Explore(abv *parser.Abventure, limit int) *Report {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	report := Report{}
+
+	fired := map[string]map[int]bool{} // Cell hash to the line numbers that fired
+	held := bitset.Set{}
+	locks := []State{}
+
+	walk(abv, limit, func(state State, scene *parser.Scene) bool {
+		report.States++
+		if fired[state.Cell] == nil {
+			fired[state.Cell] = map[int]bool{}
+		}
+		for _, num := range scene.Fired {
+			fired[state.Cell][num] = true
+		}
+		for _, slot := range scene.State.Items.Bits() {
+			held.Add(slot)
+		}
+
+		ways := 0
+		for _, choice := range scene.Choices() {
+			if !choice.Broken {
+				ways++
+			}
+		}
+		if ways == 0 {
+			locks = append(locks, state)
+		}
+		return false
+	}, func() {
+		report.Truncated = true
+	})
+
+	for _, cell := range sortedCells(abv) {
+		cellHash := hash.Single(cell.Name)
+		lines, reached := fired[cellHash]
+		if !reached {
+			report.UnreachableCells = append(report.UnreachableCells, cell.Name)
+			continue
+		}
+		for num, line := range cell.Lines {
+			if !lines[num] {
+				report.UnreachableLines = append(report.UnreachableLines, Line{Cell: cell.Name, Pos: line.Pos, Text: line.Text})
+			}
+		}
+	}
+
+	for name, item := range abv.Inventory.Items {
+		if !held.Has(item.Slot) {
+			report.UnobtainableItems = append(report.UnobtainableItems, name)
+		}
+	}
+	sort.Slice(report.UnobtainableItems, func(i, j int) bool {
+		return abv.Inventory.Items[report.UnobtainableItems[i]].Slot < abv.Inventory.Items[report.UnobtainableItems[j]].Slot
+	})
+
+	for _, state := range locks {
+		report.SoftLocks = append(report.SoftLocks, Lock{
+			Cell:  abv.Cells[state.Cell].Name,
+			Items: abv.Inventory.Names(state.Inventory),
+		})
+	}
+
+	return &report
+}
+
+// walk calls visit for every state reachable from the start, nearest first, until visit returns true.
+// If more than limit states are found, truncated is called and the walk stops.
+// The map it returns tells how each state found was first reached.
+func walk(abv *parser.Abventure, limit int, visit func(State, *parser.Scene) bool, truncated func()) map[string]Step {
+	start := State{Cell: hash.PrecalcStart}
+	previous := map[string]Step{start.key(): {}}
+	queue := []State{start}
+
+	for len(queue) > 0 {
+		if len(previous) > limit {
+			truncated()
+			break
+		}
+		state := queue[0]
+		queue = queue[1:]
+
+		scene, err := abv.Evaluate(state.Cell, state.Inventory)
+		if err != nil {
+			continue // Only reachable through a broken link, which we never follow
+		}
+		if visit(state, scene) {
+			break
+		}
+
+		for _, choice := range scene.Choices() {
+			if choice.Broken {
+				continue
+			}
+			next := State{Cell: choice.Target, Inventory: choice.State}
+			if _, seen := previous[next.key()]; seen {
+				continue
+			}
+			previous[next.key()] = Step{From: state, Cell: scene.Name, Choice: choice.Text, To: next}
+			queue = append(queue, next)
+		}
+	}
+	return previous
+}
+
+// sortedCells returns the cells of an abventure in the order they are defined.
+func sortedCells(abv *parser.Abventure) []parser.AbventureCell {
+	cells := make([]parser.AbventureCell, 0, len(abv.Cells))
+	for _, cell := range abv.Cells {
+		cells = append(cells, cell)
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Pos.File != cells[j].Pos.File {
+			return cells[i].Pos.File < cells[j].Pos.File
+		}
+		return cells[i].Pos.Line < cells[j].Pos.Line
+	})
+	return cells
+}
@@ -0,0 +1,120 @@
+package explore_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/explore"
+	"github.com/demmydemon/abventure/parser"
+)
+
+const source = `Explore
+%Key A key.
+%Crown A crown, never found.
+:Start Front door
+&Key You find a key.
+?Crown You are royalty.
+?Key >Inside Unlock the door
+:Inside
+@Key The key breaks.
+>Start Go back
+?Key >Vault Open the vault
+:Vault
+:Attic
+>Start
+`
+
+func parse(t *testing.T) *parser.Abventure {
+	t.Helper()
+	abv, err := parser.Parse(strings.NewReader(source), "explore.abv", false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	return &abv
+}
+
+func TestExplore(t *testing.T) {
+	report := explore.Explore(parse(t), 0)
+
+	// Start empty, Inside holding the key, then Start again without it (where the key is found once more).
+	if report.States != 2 {
+		t.Errorf("Wrong number of reachable states: Expected 2, got %d", report.States)
+	}
+	if report.Truncated {
+		t.Error("Report was truncated for a tiny abventure")
+	}
+	if strings.Join(report.UnreachableCells, ",") != "Vault,Attic" {
+		t.Errorf("Wrong unreachable cells: Expected Vault,Attic, got %v", report.UnreachableCells)
+	}
+	if len(report.UnreachableLines) != 2 || report.UnreachableLines[0].Text != "You are royalty." || report.UnreachableLines[1].Text != "Open the vault" {
+		t.Errorf("Wrong unreachable lines: %+v", report.UnreachableLines)
+	}
+	if strings.Join(report.UnobtainableItems, ",") != "Crown" {
+		t.Errorf("Wrong unobtainable items: Expected Crown, got %v", report.UnobtainableItems)
+	}
+	if len(report.SoftLocks) != 0 {
+		t.Errorf("Unexpected soft-locks: %+v", report.SoftLocks)
+	}
+	if report.Clean() {
+		t.Error("Report with problems claims to be clean")
+	}
+}
+
+func TestSoftLock(t *testing.T) {
+	abv, err := parser.Parse(strings.NewReader("Stuck\n%Rope A rope.\n:Start\n&Rope\n>Pit Jump\n:Pit\n!Rope >Start Climb out\n>Nowhere\n"), "stuck.abv", false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	report := explore.Explore(&abv, 0)
+	if len(report.SoftLocks) != 1 || report.SoftLocks[0].Cell != "Pit" || strings.Join(report.SoftLocks[0].Items, ",") != "Rope" {
+		t.Errorf("Expected a soft-lock in Pit holding Rope, got %+v", report.SoftLocks)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	report := explore.Explore(parse(t), 1)
+	if !report.Truncated {
+		t.Error("Report was not truncated when hitting the limit")
+	}
+}
+
+func TestSolve(t *testing.T) {
+	abv, err := parser.Parse(strings.NewReader(`Solve
+%Key A key.
+:Start
+>Hall Go to the hall
+?Key >Vault Open the vault
+:Hall
+&Key You find a key.
+>Start Go back
+:Vault
+:Attic
+`), "solve.abv", false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	steps, err := explore.Solve(&abv, "Vault", nil, 0)
+	if err != nil {
+		t.Fatalf("Unexpected solve error: %s", err)
+	}
+	choices := []string{}
+	for _, step := range steps {
+		choices = append(choices, step.Cell+":"+step.Choice)
+	}
+	if strings.Join(choices, ",") != "Start:Go to the hall,Hall:Go back,Start:Open the vault" {
+		t.Errorf("Wrong solution: %v", choices)
+	}
+
+	steps, err = explore.Solve(&abv, "Hall", []string{"Key"}, 0)
+	if err != nil || len(steps) != 1 {
+		t.Errorf("Expected a single step to the hall holding the key, got %v, %v", steps, err)
+	}
+
+	if _, err := explore.Solve(&abv, "Attic", nil, 0); err != explore.ErrNoPath {
+		t.Errorf("Expected ErrNoPath for the attic, got %v", err)
+	}
+	if _, err := explore.Solve(&abv, "Basement", nil, 0); err == nil {
+		t.Error("Solving for an undefined cell did not return an error")
+	}
+}
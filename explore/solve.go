@@ -0,0 +1,72 @@
+package explore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/demmydemon/abventure/hash"
+	"github.com/demmydemon/abventure/parser"
+)
+
+// ErrNoPath is returned by Solve when every reachable state was looked at without reaching the goal.
+var ErrNoPath = errors.New("goal can not be reached")
+
+// ErrGaveUp is returned by Solve when the limit was hit before reaching the goal.
+var ErrGaveUp = errors.New("gave up looking before reaching the goal")
+
+// Step is a single choice taken on the way to a goal.
+type Step struct {
+	From   State  // Where the choice was taken
+	Cell   string // Name of the cell the choice was taken in
+	Choice string // Text of the choice taken
+	To     State  // Where the choice leads
+}
+
+// Solve finds the shortest sequence of choices from the start that ends up in the named cell holding all the named items,
+// looking at no more than limit states. If limit is zero or less, DefaultLimit is used.
+func Solve(abv *parser.Abventure, cellName string, holding []string, limit int) ([]Step, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	target := hash.Single(cellName)
+	if _, ok := abv.Cells[target]; !ok {
+		return nil, fmt.Errorf("no such cell %s", cellName)
+	}
+	for _, name := range holding {
+		if _, ok := abv.Inventory.Lookup(name); !ok {
+			return nil, fmt.Errorf("no such item %s", name)
+		}
+	}
+
+	var goal *State
+	gaveUp := false
+	previous := walk(abv, limit, func(state State, scene *parser.Scene) bool {
+		if state.Cell != target {
+			return false
+		}
+		for _, name := range holding {
+			if item, _ := abv.Inventory.Lookup(name); !scene.State.Items.Has(item.Slot) {
+				return false
+			}
+		}
+		goal = &state
+		return true
+	}, func() {
+		gaveUp = true
+	})
+
+	if goal == nil {
+		if gaveUp {
+			return nil, ErrGaveUp
+		}
+		return nil, ErrNoPath
+	}
+
+	steps := []Step{}
+	for at := *goal; at.key() != (State{Cell: hash.PrecalcStart}).key(); {
+		step := previous[at.key()]
+		steps = append([]Step{step}, steps...)
+		at = step.From
+	}
+	return steps, nil
+}
@@ -0,0 +1,58 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/demmydemon/abventure/parser"
+)
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// DOT writes the abventure as a Graphviz digraph.
+func DOT(w io.Writer, abv *parser.Abventure) error {
+	graph := Build(abv)
+
+	_, err := fmt.Fprintf(w, "digraph \"%s\" {\n    node [shape=box];\n", dotEscaper.Replace(abv.Title))
+	if err != nil {
+		return fmt.Errorf("write graph start: %w", err)
+	}
+
+	for _, node := range graph.Nodes {
+		attributes := fmt.Sprintf(`label="%s"`, dotEscaper.Replace(node.Title()))
+		if node.Start {
+			attributes += ", peripheries=2, style=bold"
+		}
+		if node.Missing {
+			attributes += ", color=red, fontcolor=red, style=dashed"
+		}
+		_, err = fmt.Fprintf(w, "    \"%s\" [%s];\n", node.Hash, attributes)
+		if err != nil {
+			return fmt.Errorf("write node %s: %w", node.Name, err)
+		}
+	}
+
+	for _, edge := range graph.Edges {
+		attributes := ""
+		if annotation := edge.Annotation(); annotation != "" {
+			attributes = fmt.Sprintf(` label="%s"`, dotEscaper.Replace(annotation))
+		}
+		if edge.Broken {
+			attributes += " color=red style=dashed"
+		}
+		if attributes != "" {
+			attributes = " [" + strings.TrimSpace(attributes) + "]"
+		}
+		_, err = fmt.Fprintf(w, "    \"%s\" -> \"%s\"%s;\n", edge.From, edge.To, attributes)
+		if err != nil {
+			return fmt.Errorf("write edge: %w", err)
+		}
+	}
+
+	_, err = io.WriteString(w, "}\n")
+	if err != nil {
+		return fmt.Errorf("write graph end: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Package graph turns the structure of an abventure into Graphviz DOT or Mermaid flowcharts, for reviewing it at a glance.
+package graph
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/demmydemon/abventure/hash"
+	"github.com/demmydemon/abventure/parser"
+)
+
+// Node is a cell in the graph. Missing nodes are cells that are linked to, but never defined.
+type Node struct {
+	Hash    string
+	Name    string
+	Label   string
+	Start   bool
+	Missing bool
+}
+
+// Title returns the node's label, or its name if it has no label.
+func (node Node) Title() string {
+	if node.Label != "" {
+		return node.Label
+	}
+	return node.Name
+}
+
+// Edge is a link from one cell to another.
+type Edge struct {
+	From      string // Hash of the cell the link is in
+	To        string // Hash of the cell linked to
+	Condition string
+	Changes   []string // Item and variable changes earlier in the cell, as +Item, -Item or Var+N, and if Cond when checked
+	Broken    bool
+}
+
+// Annotation returns the condition and item changes of the edge as a single string.
+func (edge Edge) Annotation() string {
+	parts := []string{}
+	if edge.Condition != "" {
+		parts = append(parts, "["+edge.Condition+"]")
+	}
+	return strings.Join(append(parts, edge.Changes...), " ")
+}
+
+// Graph is every cell and link in an abventure.
+type Graph struct {
+	Nodes []Node
+	Edges []Edge
+}
+
+// Build creates the graph of the given abventure. Nodes are in the order the cells are defined, file by file, with
+// missing ones last.
+func Build(abv *parser.Abventure) *Graph {
+	cells := make([]parser.AbventureCell, 0, len(abv.Cells))
+	for _, cell := range abv.Cells {
+		cells = append(cells, cell)
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Pos.File != cells[j].Pos.File {
+			return cells[i].Pos.File < cells[j].Pos.File
+		}
+		if cells[i].Pos.Line != cells[j].Pos.Line {
+			return cells[i].Pos.Line < cells[j].Pos.Line
+		}
+		return cells[i].Name < cells[j].Name
+	})
+
+	graph := Graph{}
+	missing := map[string]bool{}
+	missingNodes := []Node{}
+
+	for _, cell := range cells {
+		cellHash := hash.Single(cell.Name)
+		graph.Nodes = append(graph.Nodes, Node{
+			Hash:  cellHash,
+			Name:  cell.Name,
+			Label: cell.Label,
+			Start: cellHash == hash.PrecalcStart,
+		})
+
+		changes := []string{}
+		for _, line := range cell.Lines {
+			lineChanges := []string{}
+			if line.GiveItem != "" {
+				lineChanges = append(lineChanges, "+"+line.GiveItem)
+			}
+			if line.TakeItem != "" {
+				lineChanges = append(lineChanges, "-"+line.TakeItem)
+			}
+			for _, change := range line.Changes {
+				lineChanges = append(lineChanges, change.String())
+			}
+			earlier := append([]string{}, changes...)
+			// Changes on a checked line only happen on some paths through the cell, so later links say when.
+			for _, change := range lineChanges {
+				if line.Condition != nil {
+					change += " if " + line.Condition.String()
+				}
+				changes = append(changes, change)
+			}
+			if line.LinksTo == "" {
+				continue
+			}
+
+			// Changes on the link line itself happen whenever the link is there, which the edge condition covers.
+			edge := Edge{
+				From:    cellHash,
+				To:      hash.Single(line.LinksTo),
+				Changes: append(earlier, lineChanges...),
+			}
+			if line.Condition != nil {
+				edge.Condition = line.Condition.String()
+			}
+			if _, exists := abv.Cells[edge.To]; !exists {
+				edge.Broken = true
+				if !missing[edge.To] {
+					missing[edge.To] = true
+					missingNodes = append(missingNodes, Node{Hash: edge.To, Name: line.LinksTo, Missing: true})
+				}
+			}
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+	graph.Nodes = append(graph.Nodes, missingNodes...)
+	return &graph
+}
@@ -0,0 +1,127 @@
+package graph_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/graph"
+	"github.com/demmydemon/abventure/hash"
+	"github.com/demmydemon/abventure/parser"
+)
+
+const source = `Graph
+%Key A key.
+:Start Front door
+&Key You find a key.
+?Key >Inside Unlock the door
+>Garden
+:Inside
+`
+
+func parse(t *testing.T) *parser.Abventure {
+	t.Helper()
+	abv, err := parser.Parse(strings.NewReader(source), "graph.abv", false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	return &abv
+}
+
+func TestBuild(t *testing.T) {
+	g := graph.Build(parse(t))
+
+	if len(g.Nodes) != 3 {
+		t.Fatalf("Expected 3 nodes, got %d: %v", len(g.Nodes), g.Nodes)
+	}
+	if !g.Nodes[0].Start || g.Nodes[0].Title() != "Front door" {
+		t.Errorf("First node should be the Start cell, got %+v", g.Nodes[0])
+	}
+	if !g.Nodes[2].Missing || g.Nodes[2].Name != "Garden" {
+		t.Errorf("Last node should be the missing Garden cell, got %+v", g.Nodes[2])
+	}
+
+	if len(g.Edges) != 2 {
+		t.Fatalf("Expected 2 edges, got %d: %v", len(g.Edges), g.Edges)
+	}
+	if g.Edges[0].To != hash.Single("Inside") || g.Edges[0].Annotation() != "[Key] +Key" {
+		t.Errorf("First edge is wrong, got %+v", g.Edges[0])
+	}
+	if !g.Edges[1].Broken {
+		t.Error("Edge to undefined cell was not marked broken")
+	}
+}
+
+func TestConditionalChanges(t *testing.T) {
+	text := "Graph\n%Key A key.\n%Door A door.\n:Start\n?Door &Key Behind the door is a key.\n>Hall\n!Key >Back @Door\n:Hall\n:Back\n"
+	abv, err := parser.Parse(strings.NewReader(text), "graph.abv", false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	g := graph.Build(&abv)
+	if len(g.Edges) != 2 {
+		t.Fatalf("Expected 2 edges, got %d: %v", len(g.Edges), g.Edges)
+	}
+	if annotation := g.Edges[0].Annotation(); annotation != "+Key if Door" {
+		t.Errorf("Give on a checked line should say when it happens, got %q", annotation)
+	}
+	if annotation := g.Edges[1].Annotation(); annotation != "[!Key] +Key if Door -Door" {
+		t.Errorf("Take on the link line itself is covered by the edge condition, got %q", annotation)
+	}
+}
+
+func TestBuildIncluded(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.abv": "Graph\n<zed.abv\n:Start\n>Aside\n>Far\n:End\n",
+		"zed.abv":  ":Aside\n>End\n\n\n:Far\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	abv, err := parser.ParseFile(filepath.Join(dir, "main.abv"), false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	var names []string
+	for _, node := range graph.Build(&abv).Nodes {
+		names = append(names, node.Name)
+	}
+	if strings.Join(names, " ") != "Start End Aside Far" {
+		t.Errorf("Nodes should be in order file by file, got %v", names)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	abv := parse(t)
+	formats := map[string]struct {
+		write    func(w *bytes.Buffer) error
+		expected []string
+	}{
+		"DOT": {
+			func(w *bytes.Buffer) error { return graph.DOT(w, abv) },
+			[]string{`digraph "Graph" {`, `[label="Front door", peripheries=2`, `[label="[Key] +Key"]`, "color=red style=dashed"},
+		},
+		"Mermaid": {
+			func(w *bytes.Buffer) error { return graph.Mermaid(w, abv) },
+			[]string{"flowchart TD", `["Front door"]`, `-->|"[Key] +Key"|`, "-.->", "class cb72c5e85 start"},
+		},
+	}
+	for name, format := range formats {
+		buf := bytes.Buffer{}
+		if err := format.write(&buf); err != nil {
+			t.Errorf("%s output failed: %s", name, err)
+			continue
+		}
+		for _, want := range format.expected {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("%s output is missing %q:\n%s", name, want, buf.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/demmydemon/abventure/parser"
+)
+
+var mermaidEscaper = strings.NewReplacer(`"`, "#quot;", "\n", " ")
+
+// Mermaid writes the abventure as a Mermaid flowchart.
+func Mermaid(w io.Writer, abv *parser.Abventure) error {
+	graph := Build(abv)
+
+	_, err := io.WriteString(w, "flowchart TD\n")
+	if err != nil {
+		return fmt.Errorf("write graph start: %w", err)
+	}
+
+	for _, node := range graph.Nodes {
+		_, err = fmt.Fprintf(w, "    c%s[\"%s\"]\n", node.Hash, mermaidEscaper.Replace(node.Title()))
+		if err != nil {
+			return fmt.Errorf("write node %s: %w", node.Name, err)
+		}
+	}
+
+	for _, edge := range graph.Edges {
+		arrow := "-->"
+		if edge.Broken {
+			arrow = "-.->"
+		}
+		if annotation := edge.Annotation(); annotation != "" {
+			arrow += fmt.Sprintf("|\"%s\"|", mermaidEscaper.Replace(annotation))
+		}
+		_, err = fmt.Fprintf(w, "    c%s %s c%s\n", edge.From, arrow, edge.To)
+		if err != nil {
+			return fmt.Errorf("write edge: %w", err)
+		}
+	}
+
+	_, err = io.WriteString(w, "    classDef start stroke-width:4px\n    classDef missing stroke:#f00,color:#f00,stroke-dasharray:5 5\n")
+	if err != nil {
+		return fmt.Errorf("write graph classes: %w", err)
+	}
+	for _, node := range graph.Nodes {
+		class := ""
+		switch {
+		case node.Start:
+			class = "start"
+		case node.Missing:
+			class = "missing"
+		default:
+			continue
+		}
+		_, err = fmt.Fprintf(w, "    class c%s %s\n", node.Hash, class)
+		if err != nil {
+			return fmt.Errorf("write node class %s: %w", node.Name, err)
+		}
+	}
+	return nil
+}
@@ -4,35 +4,49 @@ package inventory
 import (
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 )
 
-// Item holds the description and ID of items
+// MaxSlot is the highest slot an item can be given explicitly, to keep inventory states from getting huge.
+const MaxSlot = 9999
+
+// Item holds the description and slot of items. The slot is the item's bit in the inventory state.
 type Item struct {
-	ID          uint64
+	Slot        int
+	Fixed       bool `json:",omitempty"` // The slot was given explicitly, rather than picked by Define
 	Description string
 }
 
+// Variable holds the slot, starting value and display format of a numeric variable.
+// The format is shown in the inventory with %d replaced by the current value, and not at all if it is empty.
+type Variable struct {
+	Slot    int
+	Initial int
+	Format  string `json:",omitempty"`
+}
+
 // Inventory holds the inventory state and the item descriptions
 type Inventory struct {
-	state   uint64
+	state   State
 	Items   map[string]Item
-	Verbose bool `json:"-"`
+	Vars    map[string]Variable `json:",omitempty"`
+	Verbose bool                `json:"-"`
 }
 
 // New creates an empty inventory with no items described
 func New() *Inventory {
-	empty := make(map[string]Item)
 	return &Inventory{
-		state: 0,
-		Items: empty,
+		Items: make(map[string]Item),
+		Vars:  make(map[string]Variable),
 	}
 }
 
 // FromExisting creates an empty inventory containing the item definition from the inventory passed to it.
 func FromExisting(inv *Inventory) *Inventory {
 	return &Inventory{
-		state:   0,
 		Items:   inv.Items,
+		Vars:    inv.Vars,
 		Verbose: inv.Verbose,
 	}
 }
@@ -43,7 +57,7 @@ func (inv *Inventory) bark(format string, a ...any) {
 	}
 }
 
-// Define stores an item description under the given name, making up an ID for it.
+// Define stores an item description under the given name, giving it the lowest free slot.
 // If the item is already defined, it just updates the description.
 func (inv *Inventory) Define(name string, desciption string) {
 
@@ -55,14 +69,81 @@ func (inv *Inventory) Define(name string, desciption string) {
 		return
 	}
 
-	slot := len(inv.Items)
-	id := uint64(1 << slot)
+	slot := inv.freeSlot()
 	item := Item{
-		ID:          id,
+		Slot:        slot,
 		Description: desciption,
 	}
 	inv.Items[name] = item
-	inv.bark("%s defined (%02d)\n", name, id)
+	inv.bark("%s defined (%02d)\n", name, slot)
+}
+
+// DefineAt stores an item description under the given name, in the given slot.
+// An item that got the slot from Define is moved to the lowest free slot, but two items can't both ask for the same one.
+// If the item is already defined, its description is updated and it is moved to the slot.
+func (inv *Inventory) DefineAt(name string, slot int, description string) error {
+	if slot < 0 || slot > MaxSlot {
+		return fmt.Errorf("item %s: slot %d is not between 0 and %d", name, slot, MaxSlot)
+	}
+	for otherName, other := range inv.Items {
+		if other.Slot != slot || otherName == name {
+			continue
+		}
+		if other.Fixed {
+			return fmt.Errorf("item %s: slot %d is already taken by %s", name, slot, otherName)
+		}
+		delete(inv.Items, otherName) // Out of the way while finding it a new slot
+		defer func(otherName string, other Item) {
+			other.Slot = inv.freeSlot()
+			inv.Items[otherName] = other
+			inv.bark("%s moved to (%02d)\n", otherName, other.Slot)
+		}(otherName, other)
+	}
+
+	inv.Items[name] = Item{
+		Slot:        slot,
+		Fixed:       true,
+		Description: description,
+	}
+	inv.bark("%s defined (%02d)\n", name, slot)
+	return nil
+}
+
+// freeSlot returns the lowest slot no item is using.
+func (inv *Inventory) freeSlot() int {
+	used := make(map[int]bool, len(inv.Items))
+	for _, item := range inv.Items {
+		used[item.Slot] = true
+	}
+	slot := 0
+	for used[slot] {
+		slot++
+	}
+	return slot
+}
+
+// DefineVar stores a numeric variable under the given name, giving it the next free slot.
+// If the variable is already defined, it just updates the starting value and format.
+func (inv *Inventory) DefineVar(name string, initial int, format string) {
+	if inv.Vars == nil {
+		inv.Vars = make(map[string]Variable)
+	}
+
+	if old, exists := inv.Vars[name]; exists {
+		old.Initial = initial
+		old.Format = format
+		inv.Vars[name] = old
+		inv.bark("$%s updated\n", name)
+		return
+	}
+
+	slot := len(inv.Vars)
+	inv.Vars[name] = Variable{
+		Slot:    slot,
+		Initial: initial,
+		Format:  format,
+	}
+	inv.bark("$%s defined (%02d) = %d\n", name, slot, initial)
 }
 
 func (inv *Inventory) Describe(name string) string {
@@ -74,7 +155,8 @@ func (inv *Inventory) Describe(name string) string {
 	return ""
 }
 
-// Contents returns descriptions of all the items currently held in this inventory, in the order they were first defined.
+// Contents returns descriptions of all the items currently held in this inventory, in the order they were first defined,
+// followed by the formatted value of every variable that has a format, in the same order.
 func (inv *Inventory) Contents() []string {
 
 	// First we get just the items we actually have in the inventory
@@ -85,9 +167,9 @@ func (inv *Inventory) Contents() []string {
 		}
 	}
 
-	// Then we sort them by ID
+	// Then we sort them by slot
 	sort.Slice(items, func(i, j int) bool {
-		return items[i].ID < items[j].ID
+		return items[i].Slot < items[j].Slot
 	})
 
 	// And finally collect their descriptions
@@ -98,22 +180,59 @@ func (inv *Inventory) Contents() []string {
 		}
 	}
 
-	return descriptions
+	return append(descriptions, inv.varContents()...)
+}
+
+func (inv *Inventory) varContents() []string {
+	names := make([]string, 0, len(inv.Vars))
+	for name, variable := range inv.Vars {
+		if variable.Format != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return inv.Vars[names[i]].Slot < inv.Vars[names[j]].Slot
+	})
+
+	formatted := make([]string, 0, len(names))
+	for _, name := range names {
+		value, _ := inv.Value(name)
+		formatted = append(formatted, strings.ReplaceAll(inv.Vars[name].Format, "%d", strconv.Itoa(value)))
+	}
+	return formatted
+}
+
+// Names returns the names of all the items held in the given state, in the order they were first defined.
+func (inv *Inventory) Names(state State) []string {
+	names := make([]string, 0, state.Items.Count())
+	for name, item := range inv.Items {
+		if state.Items.Has(item.Slot) {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return inv.Items[names[i]].Slot < inv.Items[names[j]].Slot
+	})
+	return names
 }
 
 // DebugTable dumps the current state of the inventory to STDOUT for debugging purposes.
 func (inv *Inventory) DebugTable() {
-	fmt.Println(" Has | ID | Name       | Description")
-	fmt.Println("-----+----+------------+------------")
+	fmt.Println(" Has | Slot | Name       | Description")
+	fmt.Println("-----+------+------------+------------")
 	for name, item := range inv.Items {
 		has := " "
-		if inv.Has(item.ID) {
+		if inv.Has(item.Slot) {
 			has = "*"
 		}
-		fmt.Printf("  %s  | %02d | %-10s | %s\n", has, item.ID, name, item.Description)
+		fmt.Printf("  %s  | %4d | %-10s | %s\n", has, item.Slot, name, item.Description)
+	}
+	for name, variable := range inv.Vars {
+		value, _ := inv.Value(name)
+		fmt.Printf(" %3d | %4d | $%-9s | %s\n", value, variable.Slot, name, variable.Format)
 	}
-	fmt.Println("-----+----+------------+------------")
-	fmt.Printf("State: %d\n", inv.state)
+	fmt.Println("-----+------+------------+------------")
+	fmt.Printf("State: %s\n", FormatState(inv.state))
 }
 
 // Lookup takes an item name and returns the Item struct for it, and a bool indicating if it exists or not.
@@ -124,23 +243,23 @@ func (inv *Inventory) Lookup(name string) (Item, bool) {
 }
 
 // SetState sets the inventory state, doing no checks for validity what so ever.
-func (inv *Inventory) SetState(state uint64) {
-	inv.state = state
+func (inv *Inventory) SetState(state State) {
+	inv.state = state.Clone()
 }
 
-// GetState returns the current state of what is in the inventory.
-func (inv *Inventory) GetState() uint64 {
-	return inv.state
+// GetState returns a copy of the current state of what is in the inventory.
+func (inv *Inventory) GetState() State {
+	return inv.state.Clone()
 }
 
-// Has returns if the inventory state contains the given item ID
-func (inv *Inventory) Has(itemID uint64) bool {
-	return inv.state&itemID != 0
+// Has returns if the inventory state contains the given item slot
+func (inv *Inventory) Has(slot int) bool {
+	return inv.state.Items.Has(slot)
 }
 
 // HasItem returns if the inventory state contains the given Item
 func (inv *Inventory) HasItem(item Item) bool {
-	return inv.state&item.ID != 0
+	return inv.state.Items.Has(item.Slot)
 }
 
 // HasAny returns true if any of the given names matches a held item.
@@ -205,7 +324,7 @@ func (inv *Inventory) Add(name string) bool {
 
 // AddItem uncritically adds the given item to the inventory with no checks what so ever.
 func (inv *Inventory) AddItem(item Item) {
-	inv.state = inv.state | item.ID
+	inv.state.Items.Add(item.Slot)
 }
 
 // Remove removes the named item from the inventory, returning if the operation was successful.
@@ -227,5 +346,36 @@ func (inv *Inventory) Remove(name string) bool {
 
 // RemoveItem removes the given item from the inventory with no checks what so ever.
 func (inv *Inventory) RemoveItem(item Item) {
-	inv.state = inv.state &^ item.ID
+	inv.state.Items.Remove(item.Slot)
+}
+
+// Value returns the current value of the named variable, and a bool indicating if it exists or not.
+func (inv *Inventory) Value(name string) (int, bool) {
+	variable, ok := inv.Vars[name]
+	if !ok {
+		inv.bark("Value of $%s: Not defined!\n", name)
+		return 0, false
+	}
+	return variable.Initial + inv.state.offset(variable.Slot), true
+}
+
+// SetValue sets the named variable to the given value, returning if the operation was successful.
+func (inv *Inventory) SetValue(name string, value int) bool {
+	variable, ok := inv.Vars[name]
+	if !ok {
+		inv.bark("Could not set $%s: Not defined!\n", name)
+		return false
+	}
+	inv.bark("Set $%s to %d\n", name, value)
+	inv.state.setOffset(variable.Slot, value-variable.Initial)
+	return true
+}
+
+// AddValue adds the given amount to the named variable, returning if the operation was successful.
+func (inv *Inventory) AddValue(name string, amount int) bool {
+	value, ok := inv.Value(name)
+	if !ok {
+		return false
+	}
+	return inv.SetValue(name, value+amount)
 }
@@ -1,8 +1,11 @@
 package inventory_test
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/demmydemon/abventure/bitset"
 	"github.com/demmydemon/abventure/inventory"
 )
 
@@ -22,8 +25,8 @@ func TestDefine(t *testing.T) {
 	if item.Description != desc {
 		t.Errorf("Defined item did not retain description: Expected %q, got %q", desc, item.Description)
 	}
-	if item.ID != 1 {
-		t.Errorf("Defined item did not get expected ID: Expected %d, got %d", 1, item.ID)
+	if item.Slot != 0 {
+		t.Errorf("Defined item did not get expected slot: Expected %d, got %d", 0, item.Slot)
 	}
 
 	inv.Define(name, desc2)
@@ -38,8 +41,8 @@ func TestDefine(t *testing.T) {
 	if item == item2 {
 		t.Errorf("Unexpected reference retention of previous item definition when redefining")
 	}
-	if item.ID != item2.ID {
-		t.Errorf("Redefined item has wrong ID: Expected %d, got %d", item.ID, item2.ID)
+	if item.Slot != item2.Slot {
+		t.Errorf("Redefined item has wrong slot: Expected %d, got %d", item.Slot, item2.Slot)
 	}
 }
 
@@ -47,22 +50,134 @@ func TestInventoryState(t *testing.T) {
 	inv := inventory.New()
 	inv.Define("One", "One Description")
 	inv.Define("Two", "Two Description")
-	if inv.GetState() != 0 {
-		t.Errorf("Empty inventory returns wrong state: Expected 0, got %d", inv.GetState())
+	if !inv.GetState().Items.Equal(bitset.FromUint64(0)) {
+		t.Errorf("Empty inventory returns wrong state: Expected 0, got %v", inv.GetState().Items.Bits())
 	}
 
 	inv.Add("One")
-	if inv.GetState() != 1 {
-		t.Errorf("Single item inventory has wrong state: Expected 1, got %d", inv.GetState())
+	if !inv.GetState().Items.Equal(bitset.FromUint64(1)) {
+		t.Errorf("Single item inventory has wrong state: Expected 1, got %v", inv.GetState().Items.Bits())
 	}
 
 	inv.Add("Two")
-	if inv.GetState() != 3 {
-		t.Errorf("Two item inventory has wrong state: Expected 1, got %d", inv.GetState())
+	if !inv.GetState().Items.Equal(bitset.FromUint64(3)) {
+		t.Errorf("Two item inventory has wrong state: Expected 3, got %v", inv.GetState().Items.Bits())
 	}
 
 	inv.Remove("One")
-	if inv.GetState() != 2 {
-		t.Errorf("Inventory state wrong after removing One item: Expected 2, got %d", inv.GetState())
+	if !inv.GetState().Items.Equal(bitset.FromUint64(2)) {
+		t.Errorf("Inventory state wrong after removing One item: Expected 2, got %v", inv.GetState().Items.Bits())
+	}
+}
+
+func TestManyItems(t *testing.T) {
+	inv := inventory.New()
+	for i := 0; i < 200; i++ {
+		inv.Define(fmt.Sprintf("Item%d", i), "")
+	}
+	if !inv.Add("Item199") {
+		t.Fatal("Could not add the 200th item")
+	}
+	if inv.HasAny([]string{"Item0", "Item63", "Item64", "Item135"}) {
+		t.Error("Adding the 200th item gave other items as well")
+	}
+	if !inv.HasAll([]string{"Item199"}) {
+		t.Error("The 200th item went missing after adding it")
+	}
+}
+
+func TestStateTokens(t *testing.T) {
+	known := map[string]uint64{
+		"":    0,
+		"5":   5,
+		".BQ": 5,
+	}
+	for token, number := range known {
+		state, err := inventory.ParseState(token)
+		if err != nil {
+			t.Errorf("Parsing state %q failed: %s", token, err)
+			continue
+		}
+		if !state.Items.Equal(bitset.FromUint64(number)) {
+			t.Errorf("State %q parsed wrong: Expected %d, got %v", token, number, state.Items.Bits())
+		}
+	}
+
+	if inventory.FormatState(inventory.State{Items: bitset.FromUint64(5)}) != ".BQ" {
+		t.Errorf("State formatted wrong: Expected .BQ, got %q", inventory.FormatState(inventory.State{Items: bitset.FromUint64(5)}))
+	}
+
+	if _, err := inventory.ParseState("bogus"); err == nil {
+		t.Error("Parsing a bogus state did not return an error")
+	}
+}
+
+func TestVariables(t *testing.T) {
+	inv := inventory.New()
+	inv.Define("Torch", "You hold a torch.")
+	inv.DefineVar("Gold", 10, "You have %d gold coins.")
+	inv.DefineVar("Turns", 0, "")
+
+	if value, _ := inv.Value("Gold"); value != 10 {
+		t.Errorf("Gold should start at 10, got %d", value)
+	}
+	if inventory.FormatState(inv.GetState()) != "" {
+		t.Errorf("Untouched variables should give an empty state, got %q", inventory.FormatState(inv.GetState()))
+	}
+
+	inv.AddValue("Gold", -13)
+	inv.SetValue("Turns", 2)
+	inv.Add("Torch")
+	if inv.SetValue("Silver", 1) {
+		t.Error("Setting an undefined variable should fail")
+	}
+
+	token := inventory.FormatState(inv.GetState())
+	state, err := inventory.ParseState(token)
+	if err != nil {
+		t.Fatalf("Parsing state %q failed: %s", token, err)
+	}
+	restored := inventory.FromExisting(inv)
+	restored.SetState(state)
+	if gold, _ := restored.Value("Gold"); gold != -3 {
+		t.Errorf("Gold should be -3 after a round trip through %q, got %d", token, gold)
+	}
+	if turns, _ := restored.Value("Turns"); turns != 2 {
+		t.Errorf("Turns should be 2 after a round trip through %q, got %d", token, turns)
+	}
+
+	contents := strings.Join(restored.Contents(), "|")
+	if contents != "You hold a torch.|You have -3 gold coins." {
+		t.Errorf("Contents has wrong variable display: %q", contents)
+	}
+
+	inv.SetValue("Gold", 10)
+	inv.SetValue("Turns", 0)
+	if inventory.FormatState(inv.GetState()) != ".AQ" {
+		t.Errorf("Variables back at their initial values should not be in the state, got %q", inventory.FormatState(inv.GetState()))
+	}
+}
+
+func TestExplicitSlots(t *testing.T) {
+	inv := inventory.New()
+	inv.Define("Map", "")
+	inv.Define("Torch", "")
+	if err := inv.DefineAt("Compass", 0, ""); err != nil {
+		t.Fatalf("Taking the slot of an implicit item failed: %s", err)
+	}
+	inv.Define("Rope", "")
+
+	slots := map[string]int{"Compass": 0, "Torch": 1, "Map": 2, "Rope": 3}
+	for name, slot := range slots {
+		if item, _ := inv.Lookup(name); item.Slot != slot {
+			t.Errorf("Item %s should be in slot %d, got %d", name, slot, item.Slot)
+		}
+	}
+
+	if err := inv.DefineAt("Sextant", 0, ""); err == nil {
+		t.Error("Two items asking for the same slot should fail")
+	}
+	if err := inv.DefineAt("Anchor", inventory.MaxSlot+1, ""); err == nil {
+		t.Error("A slot above MaxSlot should fail")
 	}
 }
@@ -0,0 +1,121 @@
+package inventory
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/demmydemon/abventure/bitset"
+)
+
+// State is what a player is holding: which items, and what value each variable has.
+// The zero value is an empty inventory with every variable at its initial value.
+type State struct {
+	Items  bitset.Set
+	Values []int // Indexed by variable slot, as the difference from the variable's initial value
+}
+
+// Clone returns a copy of the state that can be changed without affecting the original.
+func (state State) Clone() State {
+	clone := State{Items: state.Items.Clone()}
+	if len(state.Values) > 0 {
+		clone.Values = make([]int, len(state.Values))
+		copy(clone.Values, state.Values)
+	}
+	return clone
+}
+
+// Equal returns true if both states hold exactly the same items and values.
+func (state State) Equal(other State) bool {
+	return FormatState(state) == FormatState(other)
+}
+
+// offset returns the value of the variable in the given slot, as the difference from its initial value.
+func (state State) offset(slot int) int {
+	if slot < len(state.Values) {
+		return state.Values[slot]
+	}
+	return 0
+}
+
+// setOffset sets the value of the variable in the given slot, as the difference from its initial value.
+func (state *State) setOffset(slot int, offset int) {
+	for len(state.Values) <= slot {
+		state.Values = append(state.Values, 0)
+	}
+	state.Values[slot] = offset
+	for len(state.Values) > 0 && state.Values[len(state.Values)-1] == 0 {
+		state.Values = state.Values[:len(state.Values)-1]
+	}
+}
+
+// FormatState turns an inventory state into the token used in play URLs.
+// The empty inventory is an empty token. Anything else is a dot followed by the base64url encoded item bits,
+// and if any variable has changed, another dot followed by the base64url encoded values.
+func FormatState(state State) string {
+	items := state.Items.String()
+	values := ""
+	if len(state.Values) > 0 {
+		buffer := make([]byte, len(state.Values)*binary.MaxVarintLen64)
+		size := 0
+		for _, value := range state.Values {
+			size += binary.PutVarint(buffer[size:], int64(value))
+		}
+		values = "." + base64.RawURLEncoding.EncodeToString(buffer[:size])
+	}
+	if items == "" && values == "" {
+		return ""
+	}
+	return "." + items + values
+}
+
+// ParseState turns a token from a play URL back into an inventory state.
+// Plain decimal numbers are accepted too, as that's how the state was stored when it was a single uint64.
+func ParseState(token string) (State, error) {
+	if token == "" {
+		return State{}, nil
+	}
+	if strings.HasPrefix(token, ".") {
+		encodedItems, encodedValues, _ := strings.Cut(token[1:], ".")
+		items, err := bitset.Parse(encodedItems)
+		if err != nil {
+			return State{}, fmt.Errorf("inventory state %q: %w", token, err)
+		}
+		values, err := parseValues(encodedValues)
+		if err != nil {
+			return State{}, fmt.Errorf("inventory state %q: %w", token, err)
+		}
+		return State{Items: items, Values: values}, nil
+	}
+	number, err := strconv.ParseUint(token, 10, 64)
+	if err != nil {
+		return State{}, fmt.Errorf("inventory state %q: %w", token, err)
+	}
+	return State{Items: bitset.FromUint64(number)}, nil
+}
+
+func parseValues(encoded string) ([]int, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	buffer, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decoding values: %w", err)
+	}
+	values := []int{}
+	for len(buffer) > 0 {
+		value, size := binary.Varint(buffer)
+		if size <= 0 {
+			return nil, fmt.Errorf("decoding values: malformed number")
+		}
+		values = append(values, int(value))
+		buffer = buffer[size:]
+	}
+	state := State{}
+	for slot := len(values) - 1; slot >= 0; slot-- {
+		state.setOffset(slot, values[slot])
+	}
+	return state.Values, nil
+}
@@ -0,0 +1,170 @@
+// Package lint checks parsed abventures for problems that the parser lets through,
+// such as broken links and references to items or variables that were never defined.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/demmydemon/abventure/hash"
+	"github.com/demmydemon/abventure/manifest"
+	"github.com/demmydemon/abventure/parser"
+)
+
+// Severity tells how bad a Diagnostic is.
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+func (sev Severity) String() string {
+	if sev == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Diagnostic is a single problem found in an abventure.
+type Diagnostic struct {
+	Severity Severity
+	Pos      parser.Pos
+	Cell     string `json:",omitempty"`
+	Message  string
+}
+
+func (diag Diagnostic) String() string {
+	if diag.Cell != "" {
+		return fmt.Sprintf("%s: %s: [%s] %s", diag.Pos, diag.Severity, diag.Cell, diag.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", diag.Pos, diag.Severity, diag.Message)
+}
+
+// HasErrors returns true if any of the given diagnostics is an Error.
+func HasErrors(diags []Diagnostic) bool {
+	for _, diag := range diags {
+		if diag.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+type checker struct {
+	abv   *parser.Abventure
+	diags []Diagnostic
+}
+
+func (chk *checker) report(sev Severity, pos parser.Pos, cell string, format string, a ...any) {
+	if pos.File == "" {
+		pos.File = chk.abv.FileName
+	}
+	chk.diags = append(chk.diags, Diagnostic{
+		Severity: sev,
+		Pos:      pos,
+		Cell:     cell,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+// Check runs every check on the given abventure, returning the problems found sorted by line.
+func Check(abv *parser.Abventure) []Diagnostic {
+	chk := checker{abv: abv}
+
+	if _, ok := abv.Cells[hash.PrecalcStart]; !ok {
+		chk.report(Error, parser.Pos{}, "", "no Start cell defined")
+	}
+
+	for _, cell := range abv.Cells {
+		for _, line := range cell.Lines {
+			chk.checkLine(cell, line)
+		}
+	}
+	for _, pos := range abv.Outside {
+		chk.report(Warning, pos, "", "line is not part of any cell, so it is ignored")
+	}
+
+	sort.SliceStable(chk.diags, func(i, j int) bool {
+		a, b := chk.diags[i].Pos, chk.diags[j].Pos
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return chk.diags[i].Message < chk.diags[j].Message
+	})
+	return chk.diags
+}
+
+// CheckSlots warns about items and variables that have changed slots since the latest published version in the
+// manifest, as those changes mix up the inventory of anyone playing that version.
+func CheckSlots(abv *parser.Abventure, published *manifest.Manifest) []Diagnostic {
+	latest, ok := published.Latest()
+	if !ok || abv.Inventory == nil {
+		return nil
+	}
+	chk := checker{abv: abv}
+	for _, change := range latest.Changes(abv.Inventory) {
+		chk.report(Warning, parser.Pos{}, "", "%s", change)
+	}
+	return chk.diags
+}
+
+func (chk *checker) checkLine(cell parser.AbventureCell, line parser.AbventureLine) {
+	if line.LinksTo != "" {
+		if _, ok := chk.abv.Cells[hash.Single(line.LinksTo)]; !ok {
+			chk.report(Error, line.Pos, cell.Name, "link to undefined cell %s", line.LinksTo)
+		}
+	}
+	parser.ConditionItems(line.Condition, func(name string) {
+		chk.checkItem(cell, line, "item check", name)
+	})
+	if line.GiveItem != "" {
+		chk.checkItem(cell, line, "&"+line.GiveItem, line.GiveItem)
+	}
+	if line.TakeItem != "" {
+		chk.checkItem(cell, line, "@"+line.TakeItem, line.TakeItem)
+	}
+	parser.ConditionVars(line.Condition, func(name string) {
+		chk.checkVar(cell, line, "comparison", name)
+	})
+	for _, change := range line.Changes {
+		chk.checkVar(cell, line, "~"+change.String(), change.Var)
+	}
+	parser.PlaceholderNames(line.Text, func(placeholder string, name string) {
+		if chk.abv.Inventory != nil {
+			_, isItem := chk.abv.Inventory.Items[name]
+			_, isVar := chk.abv.Inventory.Vars[name]
+			if isItem || isVar {
+				return
+			}
+		}
+		chk.report(Warning, line.Pos, cell.Name, "placeholder %s refers to undefined item or variable %s, so it is shown as it is", placeholder, name)
+	})
+}
+
+func (chk *checker) checkItem(cell parser.AbventureCell, line parser.AbventureLine, what string, name string) {
+	if chk.abv.Inventory != nil {
+		if _, ok := chk.abv.Inventory.Items[name]; ok {
+			return
+		}
+		if _, ok := chk.abv.Inventory.Vars[name]; ok && what == "item check" {
+			return // Checking a variable by name alone is fine, giving or taking it is not.
+		}
+	}
+	chk.report(Error, line.Pos, cell.Name, "%s refers to undefined item %s", what, name)
+}
+
+func (chk *checker) checkVar(cell parser.AbventureCell, line parser.AbventureLine, what string, name string) {
+	if chk.abv.Inventory != nil {
+		if _, ok := chk.abv.Inventory.Vars[name]; ok {
+			return
+		}
+	}
+	chk.report(Error, line.Pos, cell.Name, "%s refers to undefined variable %s", what, name)
+}
@@ -0,0 +1,159 @@
+package lint_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/lint"
+	"github.com/demmydemon/abventure/parser"
+)
+
+func parseString(t *testing.T, source string) *parser.Abventure {
+	t.Helper()
+	abv, err := parser.Parse(strings.NewReader(source), "test.abv", false)
+	if err != nil {
+		t.Fatalf("parsing test abventure: %s", err)
+	}
+	return &abv
+}
+
+func TestCleanAbventure(t *testing.T) {
+	abv := parseString(t, `Clean
+%Torch A torch.
+:Start
+?Torch >Start Loop
+&Torch Take the torch.
+`)
+	diags := lint.Check(abv)
+	if len(diags) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", diags)
+	}
+}
+
+func TestProblems(t *testing.T) {
+	abv := parseString(t, `Broken
+%Torch A torch.
+:Begin
+>Nowhere Go nowhere
+?Lamp Is there a lamp?
+@Sword
+`)
+	diags := lint.Check(abv)
+
+	expected := []string{
+		"no Start cell",
+		"test.abv:4:1: error: [Begin] link to undefined cell Nowhere",
+		"test.abv:5:1: error: [Begin] item check refers to undefined item Lamp",
+		"test.abv:6:1: error: [Begin] @Sword refers to undefined item Sword",
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %d: %v", len(expected), len(diags), diags)
+	}
+	for i, want := range expected {
+		if !strings.Contains(diags[i].String(), want) {
+			t.Errorf("Diagnostic %d: Expected it to contain %q, got %q", i, want, diags[i])
+		}
+	}
+	if !lint.HasErrors(diags) {
+		t.Error("HasErrors returned false for a list of errors")
+	}
+}
+
+func TestUndefinedVariables(t *testing.T) {
+	abv := parseString(t, `Counting
+$Gold=3
+%Torch A torch.
+:Start
+?Gold ~Gold-1 Spend a coin.
+?Silver>2 ~Copper+1 Change money.
+&Gold Take all the gold.
+`)
+	diags := lint.Check(abv)
+
+	expected := []string{
+		"test.abv:6:1: error: [Start] comparison refers to undefined variable Silver",
+		"test.abv:6:1: error: [Start] ~Copper+1 refers to undefined variable Copper",
+		"test.abv:7:1: error: [Start] &Gold refers to undefined item Gold",
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %d: %v", len(expected), len(diags), diags)
+	}
+	for i, want := range expected {
+		if diags[i].String() != want {
+			t.Errorf("Diagnostic %d: Expected %q, got %q", i, want, diags[i])
+		}
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	abv := parseString(t, `Braces
+$Gold=3
+%Torch A torch.
+:Start
+You have {Gold} coins and {Torch}.
+I {love} you, \{Lamp\}, {not a placeholder}.
+{Lamp|Torch?Light.}
+`)
+	diags := lint.Check(abv)
+
+	expected := []string{
+		"test.abv:6:1: warning: [Start] placeholder {love} refers to undefined item or variable love, so it is shown as it is",
+		"test.abv:7:1: warning: [Start] placeholder {Lamp|Torch?Light.} refers to undefined item or variable Lamp, so it is shown as it is",
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %d: %v", len(expected), len(diags), diags)
+	}
+	for i, want := range expected {
+		if diags[i].String() != want {
+			t.Errorf("Diagnostic %d: Expected %q, got %q", i, want, diags[i])
+		}
+	}
+	if lint.HasErrors(diags) {
+		t.Error("Undefined placeholders should only be warnings, as they are shown as plain text")
+	}
+}
+
+func TestOutsideCells(t *testing.T) {
+	abv := parseString(t, `Floating
+%Torch A torch.
+Some text before the first cell.
+:Start
+`)
+	diags := lint.Check(abv)
+	if len(diags) != 1 || diags[0].String() != "test.abv:3:1: warning: line is not part of any cell, so it is ignored" {
+		t.Errorf("Expected a warning about the line outside any cell, got %v", diags)
+	}
+}
+
+func TestIncludedOrder(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.abv":  "Included\n<other.abv\n:Start\n>Nowhere\n>Other\n",
+		"other.abv": ":Other\n>Gone\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	abv, err := parser.ParseFile(filepath.Join(dir, "main.abv"), false)
+	if err != nil {
+		t.Fatalf("parsing test abventure: %s", err)
+	}
+	diags := lint.Check(&abv)
+
+	expected := []string{
+		filepath.Join(dir, "main.abv") + ":4:1: error: [Start] link to undefined cell Nowhere",
+		filepath.Join(dir, "other.abv") + ":2:1: error: [Other] link to undefined cell Gone",
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %d: %v", len(expected), len(diags), diags)
+	}
+	for i, want := range expected {
+		if diags[i].String() != want {
+			t.Errorf("Diagnostic %d: Expected %q, got %q", i, want, diags[i])
+		}
+	}
+}
@@ -0,0 +1,76 @@
+//go:build linux || darwin
+
+package listing_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/listing"
+)
+
+// TestChangedWhileParsing includes a named pipe, so the parse waits until the test has saved the file again.
+func TestChangedWhileParsing(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	if err := writeAbventure(dir, "story", "Story", start); err != nil {
+		t.Fatal(err)
+	}
+	idx := listing.NewIndex(dir + "/")
+	lst, _ := idx.Get("story")
+
+	pipePath := filepath.Join(dir, "parts", "slow.abv")
+	if err := os.Mkdir(filepath.Dir(pipePath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mkfifo(pipePath, 0o644); err != nil {
+		t.Skipf("Can't make a named pipe: %s", err)
+	}
+	story := filepath.Join(dir, "story.abv")
+	save := func(content string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(story, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(story, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save("Story\n:Start Beginning\n<parts/slow.abv\n", start.Add(time.Minute))
+
+	done := make(chan []listing.Change)
+	go func() {
+		changes, _ := idx.Refresh()
+		done <- changes
+	}()
+	// Opening the pipe waits until the parse opens it too, and the parse then waits for something to read.
+	pipe, err := os.OpenFile(pipePath, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	save("Story\n:Start Beginning\nSaved while parsing.\n", start.Add(2*time.Minute))
+	if _, err := pipe.WriteString("Slow.\n"); err != nil {
+		t.Fatal(err)
+	}
+	pipe.Close()
+	if changes := <-done; len(changes) != 1 || changes[0].Err != nil {
+		t.Fatalf("Expected the first save to be parsed, got %v", changes)
+	}
+
+	changes, _ := idx.Refresh()
+	if len(changes) != 1 || changes[0].Name != "story" || changes[0].Kind != listing.Changed || changes[0].Err != nil {
+		t.Fatalf("The save made while parsing should be noticed, got %v", changes)
+	}
+	abv, _, err := lst.GetAbventure()
+	if err != nil {
+		t.Fatal(err)
+	}
+	scene, err := abv.Evaluate("", inventory.State{})
+	if err != nil || len(scene.Paragraphs) != 1 || scene.Paragraphs[0].Text != "Saved while parsing." {
+		t.Errorf("The latest save should be in use, got %+v, %v", scene, err)
+	}
+}
@@ -3,31 +3,211 @@ package listing
 import (
 	"errors"
 	"fmt"
-	"io"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/demmydemon/abventure/manifest"
 	"github.com/demmydemon/abventure/parser"
 )
 
+// Listing is one abventure file in the index. The abventure is parsed by Refresh when the file shows up, and parsed
+// again when the file, a file it includes or its manifest changes. If that fails, the last version that
+// parsed stays in use, and the error is kept for the status page. A Listing is safe for concurrent use.
 type Listing struct {
-	FileName  string
-	Title     string
-	FileTime  time.Time
-	Abventure *parser.Abventure
+	FileName string
+
+	mutex    sync.Mutex // Guards everything below
+	title    string
+	meta     parser.Metadata
+	plays    int                  // How many times it has been started since the server started
+	fileTime time.Time            // When any of the files but the manifest last changed, as the server writes that itself
+	stamps   map[string]time.Time // When each file was last seen to change, as of the last refresh or parse
+	loaded   *loaded              // The parsed abventure, or nil if it hasn't been read, or never parsed
+	loading  *loaded              // The parse in progress, if any
+	err      error                // Why the file failed to parse the last time it was tried, or nil if it worked
+	failed   time.Time
+	broken   []string // The files read when it failed, which may well differ from those of the version in use
 }
 
-func (li *Listing) GetAbventure() (*parser.Abventure, error) {
-	if li.Abventure == nil {
-		abv, err := parser.ParseFile(li.FileName, false)
-		if err != nil {
-			return nil, err
+// loaded is the result of parsing an abventure. It is never changed after done is closed, so it can still be used
+// by whoever got it after the listing has moved on to a newer one.
+type loaded struct {
+	done      chan struct{}
+	abventure *parser.Abventure
+	manifest  *manifest.Manifest // The published versions, loaded along with the abventure
+	parsed    time.Time
+	files     []string             // Every file read, even if parsing failed
+	stamps    map[string]time.Time // When each watched file last changed, as of just before parsing
+	err       error
+}
+
+// Title returns the title of the abventure, from the first line of the file.
+func (li *Listing) Title() string {
+	li.mutex.Lock()
+	defer li.mutex.Unlock()
+	return li.title
+}
+
+// Played counts another start of the abventure, for sorting by the most played.
+func (li *Listing) Played() {
+	li.mutex.Lock()
+	defer li.mutex.Unlock()
+	li.plays++
+}
+
+// Metadata returns the metadata from the top of the abventure file, which is read without parsing the rest of it.
+func (li *Listing) Metadata() parser.Metadata {
+	li.mutex.Lock()
+	defer li.mutex.Unlock()
+	return li.meta
+}
+
+// GetAbventure returns the parsed abventure and its manifest, parsing the file if it hasn't been yet.
+// If several goroutines ask at the same time, the file is only parsed once. If it fails, the next call tries again.
+// While Refresh is parsing a changed file, or if the changed file is broken, the version from before is returned.
+func (li *Listing) GetAbventure() (*parser.Abventure, *manifest.Manifest, error) {
+	li.mutex.Lock()
+	if li.loaded != nil {
+		current := li.loaded
+		li.mutex.Unlock()
+		return current.abventure, current.manifest, nil
+	}
+	if li.loading != nil {
+		pending := li.loading
+		li.mutex.Unlock()
+		<-pending.done
+		return pending.abventure, pending.manifest, pending.err
+	}
+	pending := &loaded{done: make(chan struct{})}
+	li.loading = pending
+	li.mutex.Unlock()
+
+	li.finish(pending)
+	return pending.abventure, pending.manifest, pending.err
+}
+
+// reload parses the file again, replacing the parsed abventure only if that works out.
+func (li *Listing) reload() error {
+	pending := &loaded{done: make(chan struct{})}
+	li.mutex.Lock()
+	li.loading = pending
+	li.mutex.Unlock()
+
+	li.finish(pending)
+	return pending.err
+}
+
+// finish parses the file for pending, and makes it the current version unless it failed, or the file has changed
+// again in the meantime, making pending out of date already.
+func (li *Listing) finish(pending *loaded) {
+	li.mutex.Lock()
+	pending.stamps = modTimes(li.watched())
+	li.mutex.Unlock()
+	pending.load(li.FileName)
+
+	li.mutex.Lock()
+	if li.loading == pending {
+		li.loading = nil
+		li.err = pending.err
+		li.broken = nil
+		if pending.err == nil {
+			li.loaded = pending
+		} else {
+			li.failed = pending.parsed
+			li.broken = pending.files
 		}
-		li.Abventure = &abv
+		li.stamp(pending)
 	}
-	return li.Abventure, nil
+	li.mutex.Unlock()
+	close(pending.done)
+}
+
+func (result *loaded) load(fileName string) {
+	result.parsed = time.Now()
+	abv, err := parser.ParseFile(fileName, false)
+	result.files = abv.Files
+	if err != nil {
+		result.err = err
+		return
+	}
+	published, err := manifest.Load(fileName)
+	if err != nil {
+		result.err = err
+		return
+	}
+	// Links are tagged with the version of the slots, so a rearranged abventure is recorded before any are handed out.
+	if _, isNew, err := published.Record(fileName, abv.Inventory, result.parsed); err != nil {
+		result.err = err
+		return
+	} else if isNew {
+		// Written by this parse, so it isn't a change to parse again for.
+		lockFile := manifest.PathFor(fileName)
+		result.stamps[lockFile] = modTimes([]string{lockFile})[lockFile]
+	}
+	result.abventure = &abv
+	result.manifest = published
+}
+
+// refresh checks whether the file, one it includes, or its manifest was changed after the listing was last updated.
+// If so, it returns true, along with whether it should be parsed again right away: if it has been parsed before,
+// or failed to, so the status page is up to date.
+func (li *Listing) refresh() (changed bool, stale bool) {
+	li.mutex.Lock()
+	defer li.mutex.Unlock()
+	if !li.restamp() {
+		return false, false
+	}
+	li.loading = nil // Whatever is being parsed right now is already out of date
+	li.title, li.meta = readHeader(li.FileName)
+	return true, li.loaded != nil || li.err != nil
+}
+
+// watched returns every file the abventure is made from: the file itself, its manifest, and the files included by
+// both the version in use and the last one that failed to parse, as fixing either of them should be noticed.
+func (li *Listing) watched() []string {
+	files := []string{li.FileName, manifest.PathFor(li.FileName)}
+	if li.loaded != nil {
+		files = append(files, li.loaded.abventure.Files...)
+	}
+	return append(files, li.broken...)
+}
+
+// restamp looks up the modification times of the watched files, and returns true if any of them changed, appeared or
+// went missing since the last time. The mutex must be held.
+func (li *Listing) restamp() bool {
+	stamps := modTimes(li.watched())
+	changed := len(stamps) != len(li.stamps)
+	for file, modTime := range stamps {
+		if seen, ok := li.stamps[file]; !ok || !seen.Equal(modTime) {
+			changed = true
+		}
+		if modTime.After(li.fileTime) && file != manifest.PathFor(li.FileName) {
+			li.fileTime = modTime
+		}
+	}
+	li.stamps = stamps
+	return changed
+}
+
+// stamp records the modification times of the watched files as they were before pending was parsed, so a file saved
+// while it was being parsed is noticed by the next refresh. Files pending read for the first time are looked up now,
+// and counted as missing if they changed after parsing started, so they are noticed too. The mutex must be held.
+func (li *Listing) stamp(pending *loaded) {
+	stamps := modTimes(li.watched())
+	for file, modTime := range stamps {
+		if modTime.After(li.fileTime) && file != manifest.PathFor(li.FileName) {
+			li.fileTime = modTime
+		}
+		if before, ok := pending.stamps[file]; ok {
+			stamps[file] = before
+		} else if modTime.After(pending.parsed) {
+			stamps[file] = time.Time{}
+		}
+	}
+	li.stamps = stamps
 }
 
 type Index struct {
@@ -45,14 +225,32 @@ func NewIndex(path string) *Index {
 	return &idx
 }
 
-func (idx *Index) Refresh() error {
+// Refresh looks for abventure files that have been added, changed or removed since the last time, and returns what
+// it found. Removed files are dropped from the index right away. Added files are parsed, and so are changed files
+// that have been parsed before. If that fails, the error is in the Change, and the version from before, if any,
+// stays in use.
+func (idx *Index) Refresh() ([]Change, error) {
+	changes, err := idx.scan()
+	for num, change := range changes {
+		if change.reparse {
+			changes[num].Err = change.listing.reload()
+		}
+	}
+	return changes, err
+}
+
+// scan updates the list of abventures from the directory and returns the changes. Parsing the changed files again
+// is left to the caller, so the index isn't locked meanwhile.
+func (idx *Index) scan() ([]Change, error) {
 	idx.mutex.Lock()
 	defer idx.mutex.Unlock()
 
 	files, err := os.ReadDir(idx.path)
 	if err != nil {
-		return fmt.Errorf("reading file list: %w", err)
+		return nil, fmt.Errorf("reading file list: %w", err)
 	}
+	changes := []Change{}
+	seen := make(map[string]bool, len(files))
 	for _, file := range files {
 		if file.IsDir() {
 			continue // As we're not being recursive at all!
@@ -66,38 +264,48 @@ func (idx *Index) Refresh() error {
 		info, err := file.Info()
 		if err != nil {
 			if errors.Is(err, os.ErrNotExist) {
-				// Not sure how we'd get here, considering it's working off a file list, but okay.
-				delete(idx.listings, shortName)
-				continue
+				continue // Removed since reading the directory, so it goes along with the others below.
 			}
-			return fmt.Errorf("reading file info for %s: %w", name, err)
+			return changes, fmt.Errorf("reading file info for %s: %w", name, err)
 		}
+		seen[shortName] = true
 
 		if lst, ok := idx.listings[shortName]; ok {
-			if info.ModTime().After(lst.FileTime) {
-				lst.Abventure = nil // That is, the file has changed, so should be re-read.
-				lst.Title = readFirstLine(lst.FileName)
+			if changed, parsed := lst.refresh(); changed {
+				changes = append(changes, Change{Name: shortName, Kind: Changed, listing: lst, reparse: parsed})
 			}
 		} else {
-			idx.listings[shortName] = &Listing{
-				FileName:  idx.path + file.Name(),
-				Title:     readFirstLine(idx.path + file.Name()),
-				FileTime:  info.ModTime(),
-				Abventure: nil, // This is lazy-loaded later.
+			lst := &Listing{
+				FileName: idx.path + file.Name(),
+				fileTime: info.ModTime(),
+				loaded:   nil, // This is loaded by Refresh, once the index isn't locked.
 			}
+			lst.restamp()
+			lst.title, lst.meta = readHeader(lst.FileName)
+			idx.listings[shortName] = lst
+			changes = append(changes, Change{Name: shortName, Kind: Added, listing: lst, reparse: true})
+		}
+	}
+	for shortName, lst := range idx.listings {
+		if !seen[shortName] {
+			delete(idx.listings, shortName)
+			changes = append(changes, Change{Name: shortName, Kind: Removed, listing: lst})
 		}
 	}
-	return nil
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes, nil
 }
 
-func (idx *Index) Write(w io.Writer) error {
-	for shortname, listing := range idx.listings {
-		_, err := w.Write([]byte(`<a href="` + shortname + `/">` + listing.Title + "</a><br>\n"))
-		if err != nil {
-			return fmt.Errorf("listing output: %w", err)
-		}
+// Names returns the short name of every abventure in the index, sorted.
+func (idx *Index) Names() []string {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	names := make([]string, 0, len(idx.listings))
+	for shortName := range idx.listings {
+		names = append(names, shortName)
 	}
-	return nil
+	sort.Strings(names)
+	return names
 }
 
 func (idx *Index) Get(shortName string) (*Listing, bool) {
@@ -109,22 +317,25 @@ func (idx *Index) Get(shortName string) (*Listing, bool) {
 	return &Listing{}, false
 }
 
-func readFirstLine(path string) string {
-	file, err := os.Open(path)
-	if err != nil {
-		return err.Error()
-	}
-	out := []byte{}
-	buffer := make([]byte, 1)
-	for {
-		rd, err := file.Read(buffer)
-		if err != nil {
-			return err.Error()
-		}
-		if buffer[0] == '\n' {
-			break
+// modTimes returns the modification time of each of the files. Missing files get the zero time, so going missing
+// is a change that is only seen once, like any other.
+func modTimes(files []string) map[string]time.Time {
+	stamps := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		stamps[file] = time.Time{}
+		if info, err := os.Stat(file); err == nil {
+			stamps[file] = info.ModTime()
 		}
-		out = append(out, buffer[0:rd]...)
 	}
-	return string(out)
+	return stamps
+}
+
+// readHeader reads the title and metadata of the abventure file. Mistakes in the metadata are left for the parser to
+// report, but if there's no title to be had, the error takes its place.
+func readHeader(path string) (string, parser.Metadata) {
+	title, meta, err := parser.ReadHeaderFile(path)
+	if title == "" && err != nil {
+		return err.Error(), meta
+	}
+	return title, meta
 }
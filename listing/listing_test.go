@@ -0,0 +1,462 @@
+package listing_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/demmydemon/abventure/listing"
+	"github.com/demmydemon/abventure/parser"
+)
+
+func writeAbventure(dir, name, title string, modTime time.Time) error {
+	path := filepath.Join(dir, name+".abv")
+	err := os.WriteFile(path, []byte(title+"\n:Start Beginning\nHello there.\n"), 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(path, modTime, modTime)
+}
+
+func TestParsedOnce(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeAbventure(dir, "story", "Story", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	lst := &listing.Listing{FileName: filepath.Join(dir, "story.abv")} // An index would have parsed it already
+
+	results := make([]*parser.Abventure, 20)
+	wg := sync.WaitGroup{}
+	for num := range results {
+		wg.Add(1)
+		go func(num int) {
+			defer wg.Done()
+			abv, _, err := lst.GetAbventure()
+			if err != nil {
+				t.Error(err)
+			}
+			results[num] = abv
+		}(num)
+	}
+	wg.Wait()
+	for num, abv := range results {
+		if abv != results[0] {
+			t.Fatalf("Call %d got a different abventure than call 0, so the file was parsed more than once", num)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	if err := writeAbventure(dir, "story", "First", start); err != nil {
+		t.Fatal(err)
+	}
+	idx := listing.NewIndex(dir + "/")
+	lst, _ := idx.Get("story")
+	first, _, err := lst.GetAbventure()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx.Refresh()
+	if again, _, _ := lst.GetAbventure(); again != first {
+		t.Error("Abventure was parsed again without the file changing")
+	}
+
+	if err := writeAbventure(dir, "story", "Second", start.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	idx.Refresh()
+	second, _, err := lst.GetAbventure()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Title != "Second" || lst.Title() != "Second" {
+		t.Errorf("Changed file was not read again: got %q, listed as %q", second.Title, lst.Title())
+	}
+	if first.Title != "First" {
+		t.Errorf("Abventure handed out earlier was changed to %q", first.Title)
+	}
+}
+
+// TestConcurrentAccess is mostly useful with -race.
+func TestConcurrentAccess(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	for num := 0; num < 3; num++ {
+		if err := writeAbventure(dir, fmt.Sprintf("story%d", num), "Story", start); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx := listing.NewIndex(dir + "/")
+
+	wg := sync.WaitGroup{}
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for round := 0; round < 50; round++ {
+				name := fmt.Sprintf("story%d", (worker+round)%3)
+				switch round % 4 {
+				case 0:
+					if worker == 0 {
+						// A half-written file may fail to parse, which is fine, as long as nothing races.
+						err := writeAbventure(dir, name, fmt.Sprintf("Story %d", round), start.Add(time.Duration(round)*time.Second))
+						if err != nil {
+							t.Error(err)
+						}
+					}
+					idx.Refresh()
+				case 1:
+					idx.Find(listing.Query{Search: "story", Sort: listing.MostPlayed, PerPage: 2})
+				case 2:
+					idx.Names()
+				default:
+					lst, exist := idx.Get(name)
+					if !exist {
+						t.Errorf("Index is missing %s", name)
+						continue
+					}
+					abv, published, err := lst.GetAbventure()
+					if err == nil && (abv == nil || published == nil) {
+						t.Errorf("%s loaded without an abventure or manifest", name)
+					}
+					lst.Played()
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	if found := idx.Find(listing.Query{}); found.Total != 3 || len(found.Entries) != 3 {
+		t.Errorf("Index should list all three abventures: %+v", found)
+	}
+}
+
+func TestRefreshChanges(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	if err := writeAbventure(dir, "story", "First", start); err != nil {
+		t.Fatal(err)
+	}
+	idx := listing.NewIndex(dir + "/")
+	lst, _ := idx.Get("story")
+	first, _, err := lst.GetAbventure()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeAbventure(dir, "other", "Other", start); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "story.abv")
+	if err := os.WriteFile(broken, []byte("Broken\n<missing.abv\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(broken, start.Add(time.Minute), start.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := idx.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Name != "other" || changes[0].Kind != listing.Added ||
+		changes[1].Name != "story" || changes[1].Kind != listing.Changed || changes[1].Err == nil {
+		t.Fatalf("Wrong changes after adding other and breaking story: %v", changes)
+	}
+	if abv, _, err := lst.GetAbventure(); err != nil || abv != first {
+		t.Errorf("Broken file should leave the version from before in use, got %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "other.abv")); err != nil {
+		t.Fatal(err)
+	}
+	changes, _ = idx.Refresh()
+	if len(changes) != 1 || changes[0].Name != "other" || changes[0].Kind != listing.Removed {
+		t.Fatalf("Wrong changes after removing other: %v", changes)
+	}
+	if _, exist := idx.Get("other"); exist {
+		t.Error("Removed abventure is still in the index")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "new.abv"), []byte("New\n<missing.abv\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changes, _ = idx.Refresh()
+	if len(changes) != 1 || changes[0].Name != "new" || changes[0].Kind != listing.Added || changes[0].Err == nil {
+		t.Fatalf("Adding a broken file should give an Added change with the error: %v", changes)
+	}
+	if status := idx.Status(); len(status) != 2 || status[0].Name != "new" || !status[0].Broken() {
+		t.Errorf("Broken added abventure should show in the status: %+v", status)
+	}
+	if err := os.Remove(filepath.Join(dir, "new.abv")); err != nil {
+		t.Fatal(err)
+	}
+	idx.Refresh()
+	if changes, _ := idx.Refresh(); len(changes) != 0 {
+		t.Errorf("Nothing changed, but got %v", changes)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	if err := writeAbventure(dir, "story", "<Story>", start); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.abv")
+	if err := os.WriteFile(broken, []byte("Broken\n<missing.abv\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	idx := listing.NewIndex(dir + "/")
+	lst, _ := idx.Get("story")
+	if _, _, err := lst.GetAbventure(); err != nil {
+		t.Fatal(err)
+	}
+	brokenListing, _ := idx.Get("broken")
+	if _, _, err := brokenListing.GetAbventure(); err == nil {
+		t.Fatal("Broken abventure parsed without errors")
+	}
+
+	status := idx.Status()
+	if len(status) != 2 || status[0].Name != "broken" || status[1].Name != "story" {
+		t.Fatalf("Wrong status list: %+v", status)
+	}
+	if !status[0].Broken() || !status[0].Parsed.IsZero() || !strings.Contains(status[0].Errors[0], "missing.abv") {
+		t.Errorf("Broken abventure should have its errors and no version in use: %+v", status[0])
+	}
+	if status[1].Broken() || status[1].Parsed.IsZero() {
+		t.Errorf("Working abventure should have a version in use and no errors: %+v", status[1])
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "story.abv"), []byte("Story\n<missing.abv\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	idx.Refresh()
+	status = idx.Status()
+	if !status[1].Broken() || status[1].Parsed.IsZero() {
+		t.Errorf("Abventure that broke should keep the version from before, and show the errors: %+v", status[1])
+	}
+
+	if err := writeAbventure(dir, "story", "<Story>", time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	idx.Refresh()
+	if status = idx.Status(); status[1].Broken() {
+		t.Errorf("Fixed abventure still shows errors: %+v", status[1])
+	}
+
+	buf := bytes.Buffer{}
+	if err := idx.WriteStatus(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "&lt;Story&gt;") || !strings.Contains(buf.String(), `<tr class="broken">`) {
+		t.Errorf("Status page is missing the escaped title or the broken abventure:\n%s", buf.String())
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	idx := listing.NewIndex(dir + "/")
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	log := &syncBuffer{}
+	go func() {
+		idx.Watch(time.Millisecond, stop, log)
+		close(done)
+	}()
+
+	if err := writeAbventure(dir, "story", "Story", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(log.String(), "story: added") {
+		if time.Now().After(deadline) {
+			t.Fatalf("Watch did not notice the new file, logged %q", log.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, exist := idx.Get("story"); !exist {
+		t.Error("Watch logged the new file, but it isn't in the index")
+	}
+	close(stop)
+	<-done
+}
+
+// syncBuffer is a bytes.Buffer that can be written and read at the same time.
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (buf *syncBuffer) Write(data []byte) (int, error) {
+	buf.mutex.Lock()
+	defer buf.mutex.Unlock()
+	return buf.buffer.Write(data)
+}
+
+func (buf *syncBuffer) String() string {
+	buf.mutex.Lock()
+	defer buf.mutex.Unlock()
+	return buf.buffer.String()
+}
+
+func TestListingMetadata(t *testing.T) {
+	dir := t.TempDir()
+	content := "Story\n---\nauthor: Someone\ntags: short, caves\nwarnings: spiders\n---\n:Start\n"
+	if err := os.WriteFile(filepath.Join(dir, "story.abv"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	idx := listing.NewIndex(dir + "/")
+	lst, _ := idx.Get("story")
+	if meta := lst.Metadata(); meta.Author != "Someone" || len(meta.Tags) != 2 {
+		t.Errorf("Metadata read wrong: %+v", meta)
+	}
+	found := idx.Find(listing.Query{})
+	if len(found.Entries) != 1 || found.Entries[0].Title != "Story" || found.Entries[0].Warnings[0] != "spiders" {
+		t.Errorf("Metadata missing from the entry: %+v", found)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	abventures := []struct{ name, header string }{
+		{"cave", "The Cave\n---\ntags: Fantasy, short\ndescription: Dark and damp.\n---"},
+		{"castle", "A Castle\n---\ntags: fantasy, long\nauthor: Someone\n---"},
+		{"space", "Space\n---\ntags: scifi, short\n---"},
+	}
+	for num, abventure := range abventures {
+		if err := writeAbventure(dir, abventure.name, abventure.header, start.Add(time.Duration(num)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx := listing.NewIndex(dir + "/")
+	space, _ := idx.Get("space")
+	space.Played()
+	space.Played()
+	cave, _ := idx.Get("cave")
+	cave.Played()
+
+	names := func(results listing.Results) string {
+		out := []string{}
+		for _, entry := range results.Entries {
+			out = append(out, entry.Name)
+		}
+		return strings.Join(out, ",")
+	}
+	tests := []struct {
+		query    listing.Query
+		expected string
+	}{
+		{listing.Query{}, "castle,space,cave"},
+		{listing.Query{Sort: listing.Newest}, "space,castle,cave"},
+		{listing.Query{Sort: listing.MostPlayed}, "space,cave,castle"},
+		{listing.Query{Search: "DAMP the"}, "cave"},
+		{listing.Query{Search: "someone"}, "castle"},
+		{listing.Query{Search: "nothing"}, ""},
+		{listing.Query{Tags: []string{"fantasy"}}, "castle,cave"},
+		{listing.Query{Tags: []string{"fantasy", "SHORT"}}, "cave"},
+		{listing.Query{Search: "short"}, "space,cave"},
+		{listing.Query{PerPage: 2, Page: 2}, "cave"},
+		{listing.Query{PerPage: 2, Page: 9}, "cave"},
+		{listing.Query{PerPage: 2, Page: 0}, "castle,space"},
+	}
+	for _, test := range tests {
+		if got := names(idx.Find(test.query)); got != test.expected {
+			t.Errorf("Find(%+v): Expected %q, got %q", test.query, test.expected, got)
+		}
+	}
+
+	paged := idx.Find(listing.Query{PerPage: 2, Page: 2})
+	if paged.Total != 3 || paged.Page != 2 || paged.Pages != 2 {
+		t.Errorf("Wrong page numbers: %+v", paged)
+	}
+	if tags := strings.Join(idx.Tags(), ","); tags != "fantasy,long,scifi,short" {
+		t.Errorf("Wrong tags: Expected fantasy,long,scifi,short, got %s", tags)
+	}
+}
+
+func TestMissingInclude(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"story.abv":         "Story\n<parts/chapter.abv\n",
+		"parts/chapter.abv": ":Start Beginning\nHello there.\n",
+	}
+	if err := os.Mkdir(filepath.Join(dir, "parts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx := listing.NewIndex(dir + "/")
+	lst, _ := idx.Get("story")
+	if _, _, err := lst.GetAbventure(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "parts/chapter.abv")); err != nil {
+		t.Fatal(err)
+	}
+	changes, _ := idx.Refresh()
+	if len(changes) != 1 || changes[0].Kind != listing.Changed || changes[0].Err == nil {
+		t.Fatalf("Removing the included file should be one failed change, got %v", changes)
+	}
+	for round := 0; round < 3; round++ {
+		if changes, _ := idx.Refresh(); len(changes) != 0 {
+			t.Fatalf("The missing file should only be noticed once, but refresh %d got %v", round, changes)
+		}
+	}
+}
+
+func TestBrokenInclude(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("story.abv", "Story\n:Start Beginning\n")
+	write("first.abv", "First\n<sub/first.abv\n")
+	write("sub/first.abv", ":Start Beginning\n?(Broken\n")
+	idx := listing.NewIndex(dir + "/")
+	story, _ := idx.Get("story")
+	first, _ := idx.Get("first")
+	if _, _, err := story.GetAbventure(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := first.GetAbventure(); err == nil {
+		t.Fatal("Broken include parsed without errors")
+	}
+
+	// An edit adds a broken include, which is then fixed without touching the abventure file again.
+	write("story.abv", "Story\n:Start Beginning\n<sub/story.abv\n")
+	write("sub/story.abv", "?(Broken\n")
+	if changes, _ := idx.Refresh(); len(changes) != 1 || changes[0].Err == nil {
+		t.Fatalf("Adding a broken include should be one failed change, got %v", changes)
+	}
+	write("sub/story.abv", "Fixed.\n")
+	write("sub/first.abv", ":Start Beginning\nFixed.\n")
+	changes, _ := idx.Refresh()
+	if len(changes) != 2 || changes[0].Err != nil || changes[1].Err != nil {
+		t.Fatalf("Fixing the included files should be two working changes, got %v", changes)
+	}
+	for _, status := range idx.Status() {
+		if status.Broken() {
+			t.Errorf("%s still shows errors after its include was fixed: %v", status.Name, status.Errors)
+		}
+	}
+}
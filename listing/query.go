@@ -0,0 +1,190 @@
+package listing
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/demmydemon/abventure/parser"
+)
+
+// SortOrder tells how Find orders the abventures it finds.
+type SortOrder int
+
+const (
+	ByTitle    SortOrder = iota
+	Newest               // Most recently changed first
+	MostPlayed           // Most often started since the server started first
+)
+
+var sortOrderNames = []string{"title", "newest", "played"}
+
+// SortOrders is every SortOrder, in the order they are offered.
+var SortOrders = []SortOrder{ByTitle, Newest, MostPlayed}
+
+func (order SortOrder) String() string {
+	if order < 0 || int(order) >= len(sortOrderNames) {
+		return "title"
+	}
+	return sortOrderNames[order]
+}
+
+// ParseSortOrder returns the SortOrder with the given name, or ByTitle if there is none.
+func ParseSortOrder(name string) SortOrder {
+	for num, known := range sortOrderNames {
+		if name == known {
+			return SortOrder(num)
+		}
+	}
+	return ByTitle
+}
+
+// Query picks out a page of abventures from the index.
+type Query struct {
+	Search  string   // Words that must all be found in the name, title or metadata, in any case
+	Tags    []string // Tags that must all be there, in any case
+	Sort    SortOrder
+	Page    int // Starting at 1
+	PerPage int // 0 puts everything on one page
+}
+
+// Entry is an abventure found by Find.
+type Entry struct {
+	parser.Metadata
+	Name     string
+	Title    string
+	Modified time.Time // When the file, or one it includes, last changed
+	Plays    int
+}
+
+// Results is one page of the abventures found by Find.
+type Results struct {
+	Entries []Entry
+	Total   int // How many were found, on all the pages
+	Page    int // Which page this is, starting at 1
+	Pages   int
+}
+
+func (li *Listing) entry(shortName string) Entry {
+	li.mutex.Lock()
+	defer li.mutex.Unlock()
+	return Entry{
+		Metadata: li.meta,
+		Name:     shortName,
+		Title:    li.title,
+		Modified: li.fileTime,
+		Plays:    li.plays,
+	}
+}
+
+func (idx *Index) entries() []Entry {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	entries := make([]Entry, 0, len(idx.listings))
+	for shortName, lst := range idx.listings {
+		entries = append(entries, lst.entry(shortName))
+	}
+	return entries
+}
+
+// Find returns the page of abventures the query asks for. A page past the end gives the last page.
+func (idx *Index) Find(query Query) Results {
+	found := []Entry{}
+	words := strings.Fields(strings.ToLower(query.Search))
+	for _, entry := range idx.entries() {
+		if entry.matches(words, query.Tags) {
+			found = append(found, entry)
+		}
+	}
+	sortEntries(found, query.Sort)
+
+	results := Results{Total: len(found), Page: 1, Pages: 1}
+	if query.PerPage <= 0 {
+		results.Entries = found
+		return results
+	}
+	if len(found) > query.PerPage {
+		results.Pages = (len(found) + query.PerPage - 1) / query.PerPage
+	}
+	results.Page = query.Page
+	if results.Page < 1 {
+		results.Page = 1
+	}
+	if results.Page > results.Pages {
+		results.Page = results.Pages
+	}
+	start := (results.Page - 1) * query.PerPage
+	end := start + query.PerPage
+	if end > len(found) {
+		end = len(found)
+	}
+	results.Entries = found[start:end]
+	return results
+}
+
+// matches returns true if all the words are found somewhere in the entry, and it has all the tags.
+func (entry Entry) matches(words []string, tags []string) bool {
+	for _, tag := range tags {
+		if !entry.HasTag(tag) {
+			return false
+		}
+	}
+	text := strings.ToLower(strings.Join([]string{
+		entry.Name, entry.Title, entry.Author, entry.Description,
+		strings.Join(entry.Tags, "\n"), strings.Join(entry.Warnings, "\n"),
+	}, "\n"))
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasTag returns true if the entry has the given tag, in any case.
+func (entry Entry) HasTag(tag string) bool {
+	for _, has := range entry.Tags {
+		if strings.EqualFold(has, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortEntries sorts the entries in the given order, falling back on the title and then the name, so the order is
+// the same every time.
+func sortEntries(entries []Entry, order SortOrder) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case order == Newest && !a.Modified.Equal(b.Modified):
+			return a.Modified.After(b.Modified)
+		case order == MostPlayed && a.Plays != b.Plays:
+			return a.Plays > b.Plays
+		}
+		aTitle, bTitle := strings.ToLower(a.Title), strings.ToLower(b.Title)
+		if aTitle != bTitle {
+			return aTitle < bTitle
+		}
+		return a.Name < b.Name
+	})
+}
+
+// Tags returns every tag used by any abventure in the index, sorted. Tags that only differ in case count as one,
+// spelled as in the first abventure by title to use it.
+func (idx *Index) Tags() []string {
+	entries := idx.entries()
+	sortEntries(entries, ByTitle)
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, entry := range entries {
+		for _, tag := range entry.Tags {
+			if !seen[strings.ToLower(tag)] {
+				seen[strings.ToLower(tag)] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return strings.ToLower(tags[i]) < strings.ToLower(tags[j]) })
+	return tags
+}
@@ -0,0 +1,81 @@
+package listing
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/demmydemon/abventure/parser"
+)
+
+// Status tells how an abventure in the index is doing, for whoever runs the server.
+type Status struct {
+	Name     string
+	Title    string
+	FileName string
+	Parsed   time.Time // When the version in use was parsed, or zero if there isn't one yet
+	Failed   time.Time // When parsing last failed, if it did
+	Errors   []string  // Why it failed, one parse error per line. Empty if the last try worked.
+}
+
+// Broken returns true if the file failed to parse the last time it was tried.
+func (status Status) Broken() bool {
+	return len(status.Errors) > 0
+}
+
+func (li *Listing) status(shortName string) Status {
+	li.mutex.Lock()
+	defer li.mutex.Unlock()
+	status := Status{
+		Name:     shortName,
+		Title:    li.title,
+		FileName: li.FileName,
+	}
+	if li.loaded != nil {
+		status.Parsed = li.loaded.parsed
+	}
+	if li.err != nil {
+		status.Failed = li.failed
+		lines := strings.Builder{}
+		parser.PrintError(&lines, li.err)
+		status.Errors = strings.Split(strings.TrimSuffix(lines.String(), "\n"), "\n")
+	}
+	return status
+}
+
+// Status returns the status of every abventure in the index, sorted by name.
+func (idx *Index) Status() []Status {
+	idx.mutex.RLock()
+	out := make([]Status, 0, len(idx.listings))
+	for shortName, lst := range idx.listings {
+		out = append(out, lst.status(shortName))
+	}
+	idx.mutex.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+var statusTemplate = template.Must(template.New("status").Parse(
+	`<table id="status">
+<tr><th>Abventure</th><th>File</th><th>In use</th><th>Problems</th></tr>
+{{range .}}<tr{{if .Broken}} class="broken"{{end}}>
+  <td><a href="{{.Name}}/">{{.Title}}</a></td>
+  <td>{{.FileName}}</td>
+  <td>{{if .Parsed.IsZero}}{{if .Broken}}nothing, players can't get in{{else}}not read yet{{end}}{{else}}parsed {{.Parsed.Format "2006-01-02 15:04:05"}}{{end}}</td>
+  <td>{{if .Broken}}failed {{.Failed.Format "2006-01-02 15:04:05"}}:<pre>{{range .Errors}}{{.}}
+{{end}}</pre>{{else}}none{{end}}</td>
+</tr>
+{{end}}</table>
+`))
+
+// WriteStatus writes the status of every abventure in the index as an HTML table, with parse errors included.
+func (idx *Index) WriteStatus(w io.Writer) error {
+	err := statusTemplate.Execute(w, idx.Status())
+	if err != nil {
+		return fmt.Errorf("status output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package listing
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/demmydemon/abventure/parser"
+)
+
+// ChangeKind tells what happened to an abventure file between two refreshes.
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Changed
+	Removed
+)
+
+var changeKindNames = []string{"added", "changed", "removed"}
+
+func (kind ChangeKind) String() string {
+	if kind < 0 || int(kind) >= len(changeKindNames) {
+		return "changed"
+	}
+	return changeKindNames[kind]
+}
+
+// Change is something Refresh found out about an abventure file.
+type Change struct {
+	Name string // The short name, without .abv
+	Kind ChangeKind
+	Err  error // Why an added or changed abventure could not be parsed, if it couldn't
+
+	listing *Listing
+	reparse bool // Whether the listing has to be parsed
+}
+
+func (change Change) String() string {
+	if change.Err != nil && change.Kind == Added {
+		return fmt.Sprintf("%s: %s, but fails to parse", change.Name, change.Kind)
+	}
+	if change.Err != nil {
+		return fmt.Sprintf("%s: %s, but fails to parse, so the version from before stays in use", change.Name, change.Kind)
+	}
+	return fmt.Sprintf("%s: %s", change.Name, change.Kind)
+}
+
+// Watch refreshes the index every interval until stop is closed, writing the changes it finds to log.
+// It polls rather than asking the operating system for changes, which is plenty for a directory of text files.
+func (idx *Index) Watch(interval time.Duration, stop <-chan struct{}, log io.Writer) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		changes, err := idx.Refresh()
+		if err != nil {
+			fmt.Fprintln(log, err)
+		}
+		for _, change := range changes {
+			fmt.Fprintln(log, change)
+			parser.PrintError(log, change.Err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/demmydemon/abventure/listing"
+)
+
+// listingsPerPage is how many abventures are shown on each page of the listing.
+const listingsPerPage = 20
+
+var sortLabels = map[listing.SortOrder]string{
+	listing.ByTitle:    "Title",
+	listing.Newest:     "Newest",
+	listing.MostPlayed: "Most played",
+}
+
+var listingTemplate = template.Must(template.New("listing").Funcs(template.FuncMap{"join": strings.Join}).Parse(
+	`<h2>Have an Abventure!</h2>
+<form class="search" action="/" method="get">
+	<input type="search" name="q" value="{{.Query.Search}}" placeholder="Search">
+	<select name="sort">{{range .Sorts}}<option value="{{.Value}}"{{if .Selected}} selected{{end}}>{{.Label}}</option>{{end}}</select>
+	{{- range .Query.Tags}}
+	<input type="hidden" name="tag" value="{{.}}">
+	{{- end}}
+	<button>Find</button>
+</form>
+{{with .Tags}}<p class="tags">{{range .}}<a class="tag{{if .Selected}} selected{{end}}" href="{{.Href}}">{{.Name}}</a> {{end}}</p>
+{{end}}
+{{- range .Results.Entries}}<div class="abventure"{{with .Language}} lang="{{.}}"{{end}}>
+{{- with .Cover}}<img class="cover" src="{{.}}" alt="">{{end -}}
+<a href="/{{.Name}}/">{{.Title}}</a>{{with .Author}} by {{.}}{{end}}{{with .Version}} <span class="version">{{.}}</span>{{end}}
+{{- with .Description}}<p>{{.}}</p>{{end}}
+{{- with .Tags}}<p class="tags">{{join . ", "}}</p>{{end}}
+{{- with .Warnings}}<p class="warnings">Content warnings: {{join . ", "}}</p>{{end -}}
+</div>
+{{else}}<p>No abventures found.</p>
+{{end}}
+{{- if gt .Results.Pages 1}}<p class="pages">
+{{- with .Previous}}<a href="{{.}}">Previous</a> {{end -}}
+Page {{.Results.Page}} of {{.Results.Pages}}
+{{- with .Next}} <a href="{{.}}">Next</a>{{end -}}
+</p>
+{{end}}`))
+
+type sortOption struct {
+	Value    string
+	Label    string
+	Selected bool
+}
+
+type tagLink struct {
+	Name     string
+	Href     string // Adds the tag to the filter, or takes it away if it's already there
+	Selected bool
+}
+
+type listingPage struct {
+	Query    listing.Query
+	Results  listing.Results
+	Sorts    []sortOption
+	Tags     []tagLink
+	Previous string
+	Next     string
+}
+
+// listingQuery reads what to show from the query string of the listing page: q to search for, tag to filter by,
+// sort for the order and page for the page number.
+func listingQuery(values url.Values) listing.Query {
+	page, err := strconv.Atoi(values.Get("page"))
+	if err != nil {
+		page = 1
+	}
+	return listing.Query{
+		Search:  values.Get("q"),
+		Tags:    values["tag"],
+		Sort:    listing.ParseSortOrder(values.Get("sort")),
+		Page:    page,
+		PerPage: listingsPerPage,
+	}
+}
+
+// listingURL returns the URL of the listing page showing what the query asks for, leaving out anything at its default.
+func listingURL(query listing.Query) string {
+	values := url.Values{}
+	if query.Search != "" {
+		values.Set("q", query.Search)
+	}
+	for _, tag := range query.Tags {
+		values.Add("tag", tag)
+	}
+	if query.Sort != listing.ByTitle {
+		values.Set("sort", query.Sort.String())
+	}
+	if query.Page > 1 {
+		values.Set("page", strconv.Itoa(query.Page))
+	}
+	if len(values) == 0 {
+		return "/"
+	}
+	return "/?" + values.Encode()
+}
+
+// toggleTag returns the tags with the given tag taken out if it's there, or added if it's not.
+func toggleTag(tags []string, tag string) ([]string, bool) {
+	out := []string{}
+	for _, has := range tags {
+		if !strings.EqualFold(has, tag) {
+			out = append(out, has)
+		}
+	}
+	if len(out) < len(tags) {
+		return out, true
+	}
+	return append(out, tag), false
+}
+
+func Listings(w http.ResponseWriter, r *http.Request, list *listing.Index) error {
+	query := listingQuery(r.URL.Query())
+	page := listingPage{
+		Query:   query,
+		Results: list.Find(query),
+	}
+	for _, order := range listing.SortOrders {
+		page.Sorts = append(page.Sorts, sortOption{Value: order.String(), Label: sortLabels[order], Selected: order == query.Sort})
+	}
+	for _, tag := range list.Tags() {
+		link := tagLink{Name: tag}
+		toggled := query
+		toggled.Page = 1
+		toggled.Tags, link.Selected = toggleTag(query.Tags, tag)
+		link.Href = listingURL(toggled)
+		page.Tags = append(page.Tags, link)
+	}
+	if page.Results.Page > 1 {
+		previous := query
+		previous.Page = page.Results.Page - 1
+		page.Previous = listingURL(previous)
+	}
+	if page.Results.Page < page.Results.Pages {
+		next := query
+		next.Page = page.Results.Page + 1
+		page.Next = listingURL(next)
+	}
+
+	_, err := w.Write(htmlBegin("Have an Abventure!"))
+	if err != nil {
+		return fmt.Errorf("listing write error: %w", err)
+	}
+
+	err = listingTemplate.Execute(w, page)
+	if err != nil {
+		return fmt.Errorf("listing handler: %w", err)
+	}
+
+	_, err = w.Write(htmlEnd())
+	if err != nil {
+		return fmt.Errorf("listing write error: %w", err)
+	}
+
+	return nil
+}
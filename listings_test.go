@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/listing"
+)
+
+const hostileListing = `Story 30 <b>
+---
+author: <Someone>
+description: "Quoted" & <i>slanted</i>
+tags: many, <Odd>&Tag
+language: en" onload="alert(1)
+cover: javascript:alert(1)
+---
+:Start
+Hello.
+`
+
+// writeListings writes 41 plain abventures tagged many, and one with hostile metadata that sorts in among them,
+// as the 32nd by title.
+func writeListings(t *testing.T) *listing.Index {
+	t.Helper()
+	dir := t.TempDir()
+	for num := 0; num <= 40; num++ {
+		text := fmt.Sprintf("Story %02d\n---\ntags: many\n---\n:Start\nHello.\n", num)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("story%02d.abv", num)), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "hostile.abv"), []byte(hostileListing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return listing.NewIndex(dir + "/")
+}
+
+func TestListings(t *testing.T) {
+	idx := writeListings(t)
+	w := httptest.NewRecorder()
+	if err := Listings(w, httptest.NewRequest("GET", "/?tag=many&page=2", nil), idx); err != nil {
+		t.Fatal(err)
+	}
+	page := w.Body.String()
+
+	expected := []string{
+		`<a href="/hostile/">Story 30 &lt;b&gt;</a> by &lt;Someone&gt;`,
+		`<img class="cover" src="#ZgotmplZ" alt="">`,
+		`lang="en&#34; onload=&#34;alert(1)"`,
+		`<p>&#34;Quoted&#34; &amp; &lt;i&gt;slanted&lt;/i&gt;</p>`,
+		`<p class="tags">many, &lt;Odd&gt;&amp;Tag</p>`,
+		`<input type="hidden" name="tag" value="many">`,
+		`<a class="tag selected" href="/">many</a>`,
+		`<a class="tag" href="/?tag=many&amp;tag=%3COdd%3E%26Tag">&lt;Odd&gt;&amp;Tag</a>`,
+		`<a href="/?tag=many">Previous</a> Page 2 of 3 <a href="/?page=3&amp;tag=many">Next</a>`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(page, want) {
+			t.Errorf("Listing page is missing %s:\n%s", want, page)
+		}
+	}
+	for _, unwanted := range []string{"<Someone>", "<b>", "<i>", "javascript:"} {
+		if strings.Contains(page, unwanted) {
+			t.Errorf("Listing page has %s unescaped:\n%s", unwanted, page)
+		}
+	}
+	if strings.Count(page, `<div class="abventure"`) != 20 || strings.Contains(page, "Story 19") ||
+		strings.Contains(page, "Story 39") {
+		t.Errorf("Page 2 should have the 21st to 40th abventures by title:\n%s", page)
+	}
+
+	w = httptest.NewRecorder()
+	if err := Listings(w, httptest.NewRequest("GET", "/?q=nowhere", nil), idx); err != nil {
+		t.Fatal(err)
+	}
+	if page := w.Body.String(); !strings.Contains(page, "No abventures found.") || strings.Contains(page, `class="pages"`) {
+		t.Errorf("Search that finds nothing should say so, without pages:\n%s", page)
+	}
+}
+
+func TestListingQuery(t *testing.T) {
+	values, _ := url.ParseQuery("q=cave&tag=short&tag=dark&sort=newest&page=3")
+	query := listingQuery(values)
+	expected := listing.Query{Search: "cave", Tags: []string{"short", "dark"}, Sort: listing.Newest, Page: 3, PerPage: listingsPerPage}
+	if !reflect.DeepEqual(query, expected) {
+		t.Errorf("Wrong query: %+v", query)
+	}
+	if href := listingURL(query); href != "/?page=3&q=cave&sort=newest&tag=short&tag=dark" {
+		t.Errorf("Wrong URL for the query: %s", href)
+	}
+
+	values, _ = url.ParseQuery("sort=sideways&page=first")
+	query = listingQuery(values)
+	if query.Sort != listing.ByTitle || query.Page != 1 {
+		t.Errorf("Unknown sort and bad page should give the defaults: %+v", query)
+	}
+	if href := listingURL(query); href != "/" {
+		t.Errorf("Default query should give the plain listing URL, got %s", href)
+	}
+}
+
+func TestToggleTag(t *testing.T) {
+	tags, selected := toggleTag([]string{"short", "Dark"}, "dark")
+	if !selected || !reflect.DeepEqual(tags, []string{"short"}) {
+		t.Errorf("Tag that is there should be taken away in any case: %v, %v", tags, selected)
+	}
+	tags, selected = toggleTag([]string{"short"}, "dark")
+	if selected || !reflect.DeepEqual(tags, []string{"short", "dark"}) {
+		t.Errorf("Tag that isn't there should be added: %v, %v", tags, selected)
+	}
+}
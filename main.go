@@ -1,57 +1,72 @@
 package main
 
 import (
+	"bytes"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strconv"
 	"time"
 
+	"github.com/demmydemon/abventure/explore"
+	"github.com/demmydemon/abventure/graph"
+	"github.com/demmydemon/abventure/hash"
+	"github.com/demmydemon/abventure/inventory"
 	"github.com/demmydemon/abventure/listing"
+	"github.com/demmydemon/abventure/manifest"
 	"github.com/demmydemon/abventure/parser"
+	"github.com/demmydemon/abventure/render"
+	"github.com/demmydemon/abventure/signing"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-var isNumber = regexp.MustCompile(`^[0-9]+$`)
-
 //go:embed etc/*
 var embedded embed.FS
 
-type Listing struct {
-	Name      string
-	File      string
-	Time      time.Time
-	Abventure *parser.Abventure
-}
+// watchInterval is how often the abventures directory is checked for added, changed and removed files.
+const watchInterval = 2 * time.Second
 
-func htmlBegin(title string) []byte {
-	return []byte(`<!DOCTYPE html>
+var pageTemplates = template.Must(template.New("page").Parse(`
+{{- define "begin" -}}
+<!DOCTYPE html>
 <html>
 	<head>
-		<title>` + title + `</title>
+		<title>{{.}}</title>
 		<link rel="stylesheet" href="/etc/style.css">
 	</head>
 	<body>
-`)
-}
-func htmlEnd() []byte {
-	return []byte(`
+{{end}}
+{{- define "end"}}
 	</body>
 </html>
-`)
+{{end}}
+{{- define "nocell"}}<h2>No such cell {{.}}</h2>
+{{end}}`))
+
+// htmlBegin returns the start of a page with the given title, escaped.
+func htmlBegin(title string) []byte {
+	buffer := bytes.Buffer{}
+	pageTemplates.ExecuteTemplate(&buffer, "begin", title)
+	return buffer.Bytes()
+}
+
+func htmlEnd() []byte {
+	buffer := bytes.Buffer{}
+	pageTemplates.ExecuteTemplate(&buffer, "end", nil)
+	return buffer.Bytes()
 }
 
 func parseAbventure(w http.ResponseWriter, name string) {
 	w.Header().Add("Content-Type", "text/plain")
 	abv, err := parser.ParseFile(filepath.Clean("abventures/"+name+".abv"), true)
 	if err != nil {
-		w.Write([]byte(err.Error()))
+		parser.PrintError(w, err)
 		return
 	}
 	abvJSON, err := json.MarshalIndent(abv, "", "  ")
@@ -62,7 +77,63 @@ func parseAbventure(w http.ResponseWriter, name string) {
 	w.Write(abvJSON)
 }
 
-func onAbventure(w http.ResponseWriter, name string, cell string, stuff uint64, idx *listing.Index) {
+func graphAbventure(w http.ResponseWriter, name string, writeGraph func(io.Writer, *parser.Abventure) error) {
+	w.Header().Add("Content-Type", "text/plain")
+	abv, err := parser.ParseFile(filepath.Clean("abventures/"+name+".abv"), false)
+	if err != nil {
+		parser.PrintError(w, err)
+		return
+	}
+	err = writeGraph(w, &abv)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// solveAbventure writes the shortest way to reach the given cell holding the given items, with a play URL for each step.
+func solveAbventure(w http.ResponseWriter, name string, cell string, holding []string, signer *signing.Signer) {
+	w.Header().Add("Content-Type", "text/plain")
+	if cell == "" {
+		w.Write([]byte("Usage: /" + name + ".solve?cell=<cell>&has=<item>&has=<item>...\n"))
+		return
+	}
+	abv, err := parser.ParseFile(filepath.Clean("abventures/"+name+".abv"), false)
+	if err != nil {
+		parser.PrintError(w, err)
+		return
+	}
+	published, err := manifest.Load(abv.FileName)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return
+	}
+	version, _, err := published.Record(abv.FileName, abv.Inventory, time.Now())
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return
+	}
+	steps, err := explore.Solve(&abv, cell, holding, 0)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return
+	}
+	fmt.Fprintf(w, "%d choices from Start to %s\n", len(steps), cell)
+	fmt.Fprintf(w, "     /%s/%s\n", name, signer.Token(name, hash.PrecalcStart, version.Version, inventory.State{}))
+	for num, step := range steps {
+		fmt.Fprintf(w, "%3d. [%s] %s\n", num+1, step.Cell, step.Choice)
+		fmt.Fprintf(w, "     /%s/%s\n", name, signer.Token(name, step.To.Cell, version.Version, step.To.Inventory))
+	}
+}
+
+// playLink returns a LinkFunc for the named abventure, linking relative to the current play URL.
+// The links are tagged with the given published version.
+func playLink(signer *signing.Signer, name string, version int) render.LinkFunc {
+	return func(cellHash string, state inventory.State) string {
+		return "./" + signer.Token(name, cellHash, version, state)
+	}
+}
+
+func onAbventure(w http.ResponseWriter, name string, cell string, version int, stuff inventory.State, idx *listing.Index, signer *signing.Signer) {
 	w.Header().Add("Content-Type", "text/html")
 
 	lst, exist := idx.Get(name)
@@ -73,10 +144,24 @@ func onAbventure(w http.ResponseWriter, name string, cell string, stuff uint64,
 		}
 		return
 	}
-	abv, err := lst.GetAbventure()
+	abv, published, err := lst.GetAbventure()
 	if err != nil {
-		fmt.Println(err)
-		_, err = w.Write([]byte("Something very bad happened while loading your abventure!"))
+		// There is no version that parses to fall back on. The errors are on the status page for the author.
+		fmt.Printf("abventure: %s: fails to parse\n", name)
+		_, err = w.Write([]byte("This abventure is being worked on, and can't be played right now. Please try again in a little while!"))
+		if err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+	if cell == "" {
+		lst.Played() // Coming in without a cell means starting over
+	}
+
+	stuff, err = published.Migrate(version, stuff, abv.Inventory)
+	if err != nil {
+		fmt.Printf("abventure: %s: %s\n", name, err)
+		_, err = w.Write([]byte(`Something weird about that inventory!`))
 		if err != nil {
 			fmt.Println(err)
 		}
@@ -93,7 +178,15 @@ func onAbventure(w http.ResponseWriter, name string, cell string, stuff uint64,
 
 	//abv.Inventory.Verbose = true
 
-	abv.TickCell(w, cell, stuff)
+	scene, err := abv.Evaluate(cell, stuff)
+	if err != nil {
+		pageTemplates.ExecuteTemplate(w, "nocell", cell)
+	} else {
+		err = render.HTML{Link: playLink(signer, name, published.Current(abv.Inventory))}.Render(w, scene)
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
 
 	_, err = w.Write(htmlEnd())
 	if err != nil {
@@ -120,8 +213,10 @@ func dumpFile(w http.ResponseWriter, r *http.Request, filename string) {
 func main() {
 
 	idx := listing.NewIndex("abventures/")
+	go idx.Watch(watchInterval, nil, os.Stdout)
 
 	dumperEnabled := os.Getenv("ABVDUMPER") != ""
+	signer := signing.New(os.Getenv("ABVSECRET"))
 	port := os.Getenv("ABVPORT")
 	if port == "" {
 		port = "8187"
@@ -130,6 +225,10 @@ func main() {
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 
+	if signer != nil {
+		fmt.Println("Play URLs are signed, tampered links will start over")
+	}
+
 	if dumperEnabled {
 		fmt.Println("WARNING: ABV DUMPER IS ENABLED")
 		r.Get("/{abventure:[a-zA-Z0-9_-]+}.json", func(w http.ResponseWriter, r *http.Request) {
@@ -140,62 +239,84 @@ func main() {
 			name := chi.URLParam(r, "abventure")
 			dumpFile(w, r, filepath.Clean("abventures/"+name+".abv"))
 		})
+		r.Get("/{abventure:[a-zA-Z0-9_-]+}.dot", func(w http.ResponseWriter, r *http.Request) {
+			name := chi.URLParam(r, "abventure")
+			graphAbventure(w, name, graph.DOT)
+		})
+		r.Get("/{abventure:[a-zA-Z0-9_-]+}.mmd", func(w http.ResponseWriter, r *http.Request) {
+			name := chi.URLParam(r, "abventure")
+			graphAbventure(w, name, graph.Mermaid)
+		})
+		r.Get("/{abventure:[a-zA-Z0-9_-]+}.solve", func(w http.ResponseWriter, r *http.Request) {
+			name := chi.URLParam(r, "abventure")
+			query := r.URL.Query()
+			solveAbventure(w, name, query.Get("cell"), query["has"], signer)
+		})
+		r.Get("/status", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Content-Type", "text/html")
+			err := statusPage(w, idx)
+			if err != nil {
+				fmt.Println(err)
+			}
+		})
 	}
 
 	r.Get("/{abventure:[a-zA-Z0-9_-]+}/", func(w http.ResponseWriter, r *http.Request) {
 		name := chi.URLParam(r, "abventure")
-		onAbventure(w, name, "", 0, idx)
+		onAbventure(w, name, "", 0, inventory.State{}, idx, signer)
 
 	})
-	r.Get("/{abventure:[a-zA-Z0-9_-]+}/{cell:[a-f0-9]{8,}}", func(w http.ResponseWriter, r *http.Request) {
+	r.Get("/{abventure:[a-zA-Z0-9_-]+}/{cell:[a-f0-9]{8}[a-zA-Z0-9._~-]*}", func(w http.ResponseWriter, r *http.Request) {
 		name := chi.URLParam(r, "abventure")
 		rawCell := chi.URLParam(r, "cell")
 
-		cell, stuff := rawCell[:8], rawCell[8:]
-		if !isNumber.MatchString(stuff) {
-			stuff = "0"
+		cell, version, invState, err := signer.ParseToken(name, rawCell)
+		if errors.Is(err, signing.ErrBadSignature) {
+			fmt.Printf("[%s] abventure: %s, token: %s: bad signature, starting over\n", r.RemoteAddr, name, rawCell)
+			http.Redirect(w, r, "/"+name+"/", http.StatusSeeOther)
+			return
 		}
-
-		invState, err := strconv.ParseUint(stuff, 10, 64)
 		if err != nil {
 			w.Write([]byte(`Something weird about that inventory!`))
 			return
 		}
 
-		fmt.Printf("[%s] abventure: %s, cell: %s, stuff: %d\n", r.RemoteAddr, name, cell, invState)
+		fmt.Printf("[%s] abventure: %s, cell: %s, version: %d, stuff: %q\n", r.RemoteAddr, name, cell, version, inventory.FormatState(invState))
 
-		onAbventure(w, name, cell, invState, idx)
+		onAbventure(w, name, cell, version, invState, idx, signer)
 	})
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Content-Type", "text/html")
 		Listings(w, r, idx)
 	})
 
+	r.Route("/api/v1", func(r chi.Router) {
+		apiRoutes(r, idx, signer)
+	})
+
 	r.Handle("/etc/*", http.FileServer(http.FS(embedded)))
 
 	fmt.Println("Will listen on port", port)
 	panic(http.ListenAndServe(":"+port, r))
 }
 
-func Listings(w http.ResponseWriter, r *http.Request, list *listing.Index) error {
-	_, err := w.Write(htmlBegin("Have an Abventure!"))
+// statusPage shows which version of each abventure is in use, and why any of them fail to parse.
+func statusPage(w http.ResponseWriter, idx *listing.Index) error {
+	_, err := w.Write(htmlBegin("Abventure status"))
 	if err != nil {
-		return fmt.Errorf("listing write error: %w", err)
+		return fmt.Errorf("status write error: %w", err)
 	}
 
-	w.Write([]byte("<h2>Have an Abventure!</h2>"))
+	w.Write([]byte("<h2>Abventure status</h2>\n"))
 
-	list.Refresh()
-
-	err = list.Write(w)
+	err = idx.WriteStatus(w)
 	if err != nil {
-		return fmt.Errorf("listing handler: %w", err)
+		return fmt.Errorf("status handler: %w", err)
 	}
 
 	_, err = w.Write(htmlEnd())
 	if err != nil {
-		return fmt.Errorf("listing write error: %w", err)
+		return fmt.Errorf("status write error: %w", err)
 	}
-
 	return nil
 }
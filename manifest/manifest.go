@@ -0,0 +1,245 @@
+// Package manifest keeps track of which item and variable went in which slot for every published version of an
+// abventure, so changes that would break the inventory of players part way through can be caught.
+//
+// The manifest of an abventure file is kept next to it, with .lock added to the name, as in example.abv.lock.
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/demmydemon/abventure/inventory"
+)
+
+// ErrUnknownVersion is returned by Migrate for versions that were never published.
+var ErrUnknownVersion = errors.New("no such published version")
+
+// Version is the slots of a single published version.
+type Version struct {
+	Version   int
+	Published time.Time
+	Items     []string // Item names by slot, with an empty string for unused slots
+	Vars      []string `json:",omitempty"` // Variable names by slot
+}
+
+// Manifest is every published version of an abventure, oldest first.
+type Manifest struct {
+	Versions []Version
+}
+
+// PathFor returns the path of the manifest for the given abventure file.
+func PathFor(abvFile string) string {
+	return abvFile + ".lock"
+}
+
+// Load reads the manifest for the given abventure file. If there is none, an empty manifest is returned.
+func Load(abvFile string) (*Manifest, error) {
+	data, err := os.ReadFile(PathFor(abvFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return &Manifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load manifest: %w", err)
+	}
+	manifest := Manifest{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("load manifest %s: %w", PathFor(abvFile), err)
+	}
+	return &manifest, nil
+}
+
+// Save writes the manifest for the given abventure file, replacing the old one in a single step.
+func (manifest *Manifest) Save(abvFile string) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("save manifest: %w", err)
+	}
+	temp := PathFor(abvFile) + ".tmp"
+	if err := os.WriteFile(temp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("save manifest: %w", err)
+	}
+	if err := os.Rename(temp, PathFor(abvFile)); err != nil {
+		return fmt.Errorf("save manifest: %w", err)
+	}
+	return nil
+}
+
+// Latest returns the most recently published version, and false if nothing has been published yet.
+func (manifest *Manifest) Latest() (Version, bool) {
+	if len(manifest.Versions) == 0 {
+		return Version{}, false
+	}
+	return manifest.Versions[len(manifest.Versions)-1], true
+}
+
+// Get returns the given version, and false if there is no such version.
+func (manifest *Manifest) Get(version int) (Version, bool) {
+	for _, published := range manifest.Versions {
+		if published.Version == version {
+			return published, true
+		}
+	}
+	return Version{}, false
+}
+
+// Publish adds the slots of the inventory as a new version, unless they are the same as the latest version.
+// It returns the version the inventory matches, and true if it is a new one.
+func (manifest *Manifest) Publish(inv *inventory.Inventory, now time.Time) (Version, bool) {
+	current := Snapshot(inv)
+	latest, ok := manifest.Latest()
+	if ok && latest.Same(current) {
+		return latest, false
+	}
+	current.Version = latest.Version + 1
+	current.Published = now
+	manifest.Versions = append(manifest.Versions, current)
+	return current, true
+}
+
+// Record publishes the slots of the inventory as a new version and saves the manifest for the given abventure file,
+// unless they are the same as the latest version already. Play links are only handed out after this, so that every
+// link carries a version its state can be migrated from, even if the abventure was rearranged without publishing it.
+// It returns the version the inventory matches, and true if it is a new one.
+func (manifest *Manifest) Record(abvFile string, inv *inventory.Inventory, now time.Time) (Version, bool, error) {
+	version, isNew := manifest.Publish(inv, now)
+	if !isNew {
+		return version, false, nil
+	}
+	if err := manifest.Save(abvFile); err != nil {
+		manifest.Versions = manifest.Versions[:len(manifest.Versions)-1]
+		return Version{}, false, err
+	}
+	return version, true, nil
+}
+
+// Current returns the number of the latest published version if the slots of the inventory still match it, or 0 if
+// they don't, or nothing has been published. Play URLs are tagged with this, so they can be migrated later on.
+// After Record, it is always the recorded version.
+func (manifest *Manifest) Current(inv *inventory.Inventory) int {
+	latest, ok := manifest.Latest()
+	if !ok || !latest.Same(Snapshot(inv)) {
+		return 0
+	}
+	return latest.Version
+}
+
+// Migrate moves an inventory state from the slots of the given published version to the current slots of the
+// inventory, going by the names of the items and variables. Anything that is no longer defined is dropped, and
+// variables keep how far they were from their starting value.
+// Version 0, and the version the inventory currently matches, leave the state as it is. Version 0 is only found in
+// links from before the slots were first recorded.
+func (manifest *Manifest) Migrate(version int, state inventory.State, inv *inventory.Inventory) (inventory.State, error) {
+	if version == 0 || version == manifest.Current(inv) {
+		return state, nil
+	}
+	published, ok := manifest.Get(version)
+	if !ok {
+		return inventory.State{}, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	migrated := inventory.FromExisting(inv)
+	for _, slot := range state.Items.Bits() {
+		if slot < len(published.Items) && published.Items[slot] != "" {
+			migrated.Add(published.Items[slot])
+		}
+	}
+	for slot, offset := range state.Values {
+		if slot >= len(published.Vars) || offset == 0 {
+			continue
+		}
+		if variable, exists := inv.Vars[published.Vars[slot]]; exists {
+			migrated.SetValue(published.Vars[slot], variable.Initial+offset)
+		}
+	}
+	return migrated.GetState(), nil
+}
+
+// Snapshot returns the slots of the inventory as an unpublished version.
+func Snapshot(inv *inventory.Inventory) Version {
+	version := Version{Items: []string{}}
+	for name, item := range inv.Items {
+		for len(version.Items) <= item.Slot {
+			version.Items = append(version.Items, "")
+		}
+		version.Items[item.Slot] = name
+	}
+	for name, variable := range inv.Vars {
+		for len(version.Vars) <= variable.Slot {
+			version.Vars = append(version.Vars, "")
+		}
+		version.Vars[variable.Slot] = name
+	}
+	return version
+}
+
+// Same returns true if both versions have the same items and variables in the same slots.
+func (version Version) Same(other Version) bool {
+	return sameNames(version.Items, other.Items) && sameNames(version.Vars, other.Vars)
+}
+
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Changes describes every way the slots of the inventory differ from this version, in slot order.
+// Each of them means a player holding an inventory from this version would end up with the wrong things.
+func (version Version) Changes(inv *inventory.Inventory) []string {
+	current := map[string]int{}
+	for name, item := range inv.Items {
+		current[name] = item.Slot
+	}
+	changes := slotChanges("item", version.Version, version.Items, current)
+
+	current = map[string]int{}
+	for name, variable := range inv.Vars {
+		current[name] = variable.Slot
+	}
+	return append(changes, slotChanges("variable", version.Version, version.Vars, current)...)
+}
+
+func slotChanges(kind string, version int, published []string, current map[string]int) []string {
+	type change struct {
+		slot    int
+		message string
+	}
+	changes := []change{}
+	for slot, name := range published {
+		if name == "" {
+			continue
+		}
+		now, exists := current[name]
+		if !exists {
+			changes = append(changes, change{slot, fmt.Sprintf("%s %s was in slot %d in version %d, but is no longer defined", kind, name, slot, version)})
+		} else if now != slot {
+			changes = append(changes, change{slot, fmt.Sprintf("%s %s moved from slot %d in version %d to slot %d", kind, name, slot, version, now)})
+		}
+	}
+	for name, slot := range current {
+		if slot < len(published) && published[slot] != "" && published[slot] != name {
+			if _, stillThere := current[published[slot]]; !stillThere {
+				changes = append(changes, change{slot, fmt.Sprintf("%s %s took slot %d, which held %s in version %d", kind, name, slot, published[slot], version)})
+			}
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].slot < changes[j].slot
+	})
+	messages := make([]string, len(changes))
+	for i, change := range changes {
+		messages[i] = change.message
+	}
+	return messages
+}
@@ -0,0 +1,133 @@
+package manifest_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/manifest"
+)
+
+func TestPublish(t *testing.T) {
+	abvFile := filepath.Join(t.TempDir(), "story.abv")
+	published, err := manifest.Load(abvFile)
+	if err != nil {
+		t.Fatalf("Loading a missing manifest should give an empty one, got %s", err)
+	}
+
+	inv := inventory.New()
+	inv.Define("Map", "")
+	inv.Define("Torch", "")
+	inv.DefineVar("Gold", 0, "")
+	first, isNew := published.Publish(inv, time.Now())
+	if !isNew || first.Version != 1 || strings.Join(first.Items, ",") != "Map,Torch" || strings.Join(first.Vars, ",") != "Gold" {
+		t.Errorf("First version published wrong: %+v", first)
+	}
+	if _, isNew := published.Publish(inv, time.Now()); isNew {
+		t.Error("Publishing the same slots again should not make a new version")
+	}
+	if err := published.Save(abvFile); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := manifest.Load(abvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, ok := reloaded.Latest()
+	if !ok || !latest.Same(first) {
+		t.Errorf("Manifest did not survive saving and loading: %+v", reloaded)
+	}
+
+	changed := inventory.New()
+	changed.Define("Compass", "")
+	changed.Define("Map", "")
+	changed.DefineVar("Gold", 0, "")
+	expected := []string{
+		"item Map moved from slot 0 in version 1 to slot 1",
+		"item Torch was in slot 1 in version 1, but is no longer defined",
+		"item Map took slot 1, which held Torch in version 1",
+	}
+	if got := strings.Join(latest.Changes(changed), "|"); got != strings.Join(expected, "|") {
+		t.Errorf("Wrong changes:\nExpected %v\n     got %v", expected, latest.Changes(changed))
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	old := inventory.New()
+	old.Define("Map", "")
+	old.Define("Torch", "")
+	old.DefineVar("Gold", 0, "")
+	published := &manifest.Manifest{}
+	published.Publish(old, time.Now())
+	old.Add("Map")
+	old.Add("Torch")
+	old.SetValue("Gold", 3)
+	state := old.GetState()
+
+	inv := inventory.New()
+	inv.Define("Compass", "")
+	inv.Define("Map", "")
+	inv.DefineVar("Silver", 0, "")
+	inv.DefineVar("Gold", 10, "")
+	if current := published.Current(inv); current != 0 {
+		t.Errorf("Changed inventory should not match version 1, got version %d", current)
+	}
+
+	migrated, err := published.Migrate(1, state, inv)
+	if err != nil {
+		t.Fatalf("Unexpected migration error: %s", err)
+	}
+	check := inventory.FromExisting(inv)
+	check.SetState(migrated)
+	if names := strings.Join(check.Names(migrated), ","); names != "Map" {
+		t.Errorf("Wrong items after migration: Expected Map, got %s", names)
+	}
+	if gold, _ := check.Value("Gold"); gold != 13 {
+		t.Errorf("Wrong Gold after migration: Expected 13, got %d", gold)
+	}
+	if silver, _ := check.Value("Silver"); silver != 0 {
+		t.Errorf("Wrong Silver after migration: Expected 0, got %d", silver)
+	}
+
+	if same, err := published.Migrate(0, state, inv); err != nil || !same.Equal(state) {
+		t.Errorf("Version 0 should be left alone, got %q, %v", inventory.FormatState(same), err)
+	}
+	if _, err := published.Migrate(7, state, inv); !errors.Is(err, manifest.ErrUnknownVersion) {
+		t.Errorf("Unpublished version should fail with ErrUnknownVersion, got %v", err)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	abvFile := filepath.Join(t.TempDir(), "story.abv")
+	published := &manifest.Manifest{}
+	inv := inventory.New()
+	inv.Define("Torch", "")
+	first, isNew, err := published.Record(abvFile, inv, time.Now())
+	if err != nil || !isNew || first.Version != 1 {
+		t.Fatalf("Slots should be recorded as version 1, got %+v, %v, %v", first, isNew, err)
+	}
+	if again, isNew, err := published.Record(abvFile, inv, time.Now()); err != nil || isNew || again.Version != 1 {
+		t.Errorf("Recording the same slots again should keep version 1, got %+v, %v, %v", again, isNew, err)
+	}
+
+	inv.Define("Map", "")
+	if second, isNew, err := published.Record(abvFile, inv, time.Now()); err != nil || !isNew || second.Version != 2 {
+		t.Errorf("Changed slots should be recorded as version 2, got %+v, %v, %v", second, isNew, err)
+	}
+	reloaded, err := manifest.Load(abvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.Versions) != 2 || reloaded.Current(inv) != 2 {
+		t.Errorf("Recorded versions should be saved, got %+v", reloaded)
+	}
+
+	missing := &manifest.Manifest{}
+	if _, _, err := missing.Record(filepath.Join(abvFile, "nowhere.abv"), inv, time.Now()); err == nil || len(missing.Versions) != 0 {
+		t.Errorf("Failing to save should fail, without keeping the version: %v, %+v", err, missing)
+	}
+}
@@ -0,0 +1,296 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/demmydemon/abventure/inventory"
+)
+
+// Condition is a check against the inventory that decides if a line is shown.
+type Condition interface {
+	Eval(inv *inventory.Inventory) bool
+	String() string
+}
+
+// ItemCheck holds if the named item is in the inventory.
+// If the name is a variable rather than an item, it holds if the variable is not zero.
+type ItemCheck struct {
+	Item string
+}
+
+// Not holds if the condition it wraps does not.
+type Not struct {
+	X Condition
+}
+
+// All holds if every one of its conditions hold.
+type All []Condition
+
+// Any holds if at least one of its conditions hold.
+type Any []Condition
+
+func (check ItemCheck) Eval(inv *inventory.Inventory) bool {
+	if _, isVar := inv.Vars[check.Item]; isVar {
+		value, _ := inv.Value(check.Item)
+		return value != 0
+	}
+	return inv.HasAll([]string{check.Item})
+}
+
+func (not Not) Eval(inv *inventory.Inventory) bool {
+	return !not.X.Eval(inv)
+}
+
+func (all All) Eval(inv *inventory.Inventory) bool {
+	for _, cond := range all {
+		if !cond.Eval(inv) {
+			return false
+		}
+	}
+	return true
+}
+
+func (either Any) Eval(inv *inventory.Inventory) bool {
+	for _, cond := range either {
+		if cond.Eval(inv) {
+			return true
+		}
+	}
+	return false
+}
+
+func (check ItemCheck) String() string {
+	return check.Item
+}
+
+func (not Not) String() string {
+	switch not.X.(type) {
+	case All, Any:
+		return "!(" + not.X.String() + ")"
+	}
+	return "!" + not.X.String()
+}
+
+func (all All) String() string {
+	terms := make([]string, len(all))
+	for i, cond := range all {
+		terms[i] = cond.String()
+		if _, ok := cond.(Any); ok {
+			terms[i] = "(" + terms[i] + ")"
+		}
+	}
+	return strings.Join(terms, " & ")
+}
+
+func (either Any) String() string {
+	terms := make([]string, len(either))
+	for i, cond := range either {
+		terms[i] = cond.String()
+	}
+	return strings.Join(terms, " | ")
+}
+
+// Conditions are written to JSON the same way they're written in abventure files.
+func (check ItemCheck) MarshalText() ([]byte, error) { return []byte(check.String()), nil }
+func (not Not) MarshalText() ([]byte, error)         { return []byte(not.String()), nil }
+func (all All) MarshalText() ([]byte, error)         { return []byte(all.String()), nil }
+func (either Any) MarshalText() ([]byte, error)      { return []byte(either.String()), nil }
+
+// Both returns a condition that holds when both a and b hold. Either may be nil, meaning no condition.
+func Both(a, b Condition) Condition {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if all, ok := a.(All); ok {
+		return append(all[:len(all):len(all)], b)
+	}
+	return All{a, b}
+}
+
+// ConditionItems calls fn with the name of every item the condition checks, in the order they're written.
+// Variables checked by name alone are included, compared variables are not. See ConditionVars for those.
+func ConditionItems(cond Condition, fn func(name string)) {
+	switch cond := cond.(type) {
+	case ItemCheck:
+		fn(cond.Item)
+	case Not:
+		ConditionItems(cond.X, fn)
+	case All:
+		for _, term := range cond {
+			ConditionItems(term, fn)
+		}
+	case Any:
+		for _, term := range cond {
+			ConditionItems(term, fn)
+		}
+	}
+}
+
+// ConditionVars calls fn with the name of every variable the condition compares, in the order they're written.
+func ConditionVars(cond Condition, fn func(name string)) {
+	switch cond := cond.(type) {
+	case Compare:
+		fn(cond.Var)
+	case Not:
+		ConditionVars(cond.X, fn)
+	case All:
+		for _, term := range cond {
+			ConditionVars(term, fn)
+		}
+	case Any:
+		for _, term := range cond {
+			ConditionVars(term, fn)
+		}
+	}
+}
+
+// ParseCondition parses a condition expression, such as `Sword | (Axe & !Broken)`.
+// Item names are combined with & for and, | for or, and ! for not, grouped with parentheses.
+// And binds tighter than or, so `A | B & C` means `A | (B & C)`.
+// Variables are compared to numbers with == != < <= > or >=, as in `Gold>=5`.
+func ParseCondition(src string) (Condition, error) {
+	cp := conditionParser{src: src}
+	cond, err := cp.parseAny()
+	if err != nil {
+		return nil, err
+	}
+	cp.skipSpace()
+	if cp.pos < len(cp.src) {
+		return nil, cp.errorf("unexpected %q", cp.src[cp.pos:])
+	}
+	return cond, nil
+}
+
+type conditionParser struct {
+	src string
+	pos int
+}
+
+func (cp *conditionParser) errorf(format string, a ...any) error {
+	return fmt.Errorf("condition %q at offset %d: %s", cp.src, cp.pos, fmt.Sprintf(format, a...))
+}
+
+func (cp *conditionParser) skipSpace() {
+	for cp.pos < len(cp.src) && cp.src[cp.pos] == ' ' {
+		cp.pos++
+	}
+}
+
+// accept skips past the given character if it's next, returning if it was.
+func (cp *conditionParser) accept(char byte) bool {
+	cp.skipSpace()
+	if cp.pos < len(cp.src) && cp.src[cp.pos] == char {
+		cp.pos++
+		return true
+	}
+	return false
+}
+
+func (cp *conditionParser) parseAny() (Condition, error) {
+	terms := Any{}
+	for {
+		term, err := cp.parseAll()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+		if !cp.accept('|') {
+			break
+		}
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+func (cp *conditionParser) parseAll() (Condition, error) {
+	terms := All{}
+	for {
+		term, err := cp.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+		if !cp.accept('&') {
+			break
+		}
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+func (cp *conditionParser) parseUnary() (Condition, error) {
+	if cp.accept('!') {
+		cond, err := cp.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{X: cond}, nil
+	}
+	if cp.accept('(') {
+		cond, err := cp.parseAny()
+		if err != nil {
+			return nil, err
+		}
+		if !cp.accept(')') {
+			return nil, cp.errorf("missing )")
+		}
+		return cond, nil
+	}
+	return cp.parseName()
+}
+
+func (cp *conditionParser) parseName() (Condition, error) {
+	cp.skipSpace()
+	start := cp.pos
+	for cp.pos < len(cp.src) && isNameChar(cp.src[cp.pos]) {
+		cp.pos++
+	}
+	if cp.pos == start {
+		if cp.pos == len(cp.src) {
+			return nil, cp.errorf("expected an item name, but the condition ended")
+		}
+		return nil, cp.errorf("expected an item name, found %q", cp.src[cp.pos])
+	}
+	name := cp.src[start:cp.pos]
+	end := cp.pos
+	cp.skipSpace()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if strings.HasPrefix(cp.src[cp.pos:], op) {
+			cp.pos += len(op)
+			return cp.parseNumber(name, op)
+		}
+	}
+	cp.pos = end
+	return ItemCheck{Item: name}, nil
+}
+
+func (cp *conditionParser) parseNumber(name string, op string) (Condition, error) {
+	cp.skipSpace()
+	start := cp.pos
+	if cp.pos < len(cp.src) && cp.src[cp.pos] == '-' {
+		cp.pos++
+	}
+	for cp.pos < len(cp.src) && cp.src[cp.pos] >= '0' && cp.src[cp.pos] <= '9' {
+		cp.pos++
+	}
+	value, err := strconv.Atoi(cp.src[start:cp.pos])
+	if err != nil {
+		cp.pos = start
+		return nil, cp.errorf("expected a number to compare %s to", name)
+	}
+	return Compare{Var: name, Op: op, Value: value}, nil
+}
+
+func isNameChar(char byte) bool {
+	return char == '_' || char == '-' ||
+		(char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9')
+}
@@ -0,0 +1,93 @@
+package parser_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/bitset"
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/parser"
+)
+
+func TestParseCondition(t *testing.T) {
+	inv := inventory.New()
+	inv.Define("Sword", "")
+	inv.Define("Axe", "")
+	inv.Define("Broken", "")
+	inv.Add("Axe")
+
+	known := map[string]struct {
+		canonical string
+		holds     bool
+	}{
+		"Sword":                    {"Sword", false},
+		"!Sword":                   {"!Sword", true},
+		"Sword | Axe":              {"Sword | Axe", true},
+		"Sword|Axe&Broken":         {"Sword | Axe & Broken", false},
+		"(Sword | Axe) & !Broken":  {"(Sword | Axe) & !Broken", true},
+		"!(Sword | Axe)":           {"!(Sword | Axe)", false},
+		"( Axe & ( !Sword ) )":     {"Axe & !Sword", true},
+		"Ring-of-insight | !Sword": {"Ring-of-insight | !Sword", true},
+	}
+	for src, want := range known {
+		cond, err := parser.ParseCondition(src)
+		if err != nil {
+			t.Errorf("Parsing %q failed: %s", src, err)
+			continue
+		}
+		if cond.String() != want.canonical {
+			t.Errorf("Condition %q has wrong canonical form: Expected %q, got %q", src, want.canonical, cond.String())
+		}
+		if cond.Eval(inv) != want.holds {
+			t.Errorf("Condition %q evaluated wrong: Expected %v", src, want.holds)
+		}
+	}
+
+	for _, src := range []string{"", "Sword |", "(Sword", "Sword)", "Sword Axe", "&Sword"} {
+		if _, err := parser.ParseCondition(src); err == nil {
+			t.Errorf("Parsing bad condition %q did not return an error", src)
+		}
+	}
+}
+
+func TestGroupedConditionLines(t *testing.T) {
+	source := `Grouped
+%Sword A sword.
+%Axe An axe.
+:Start
+?(Sword | Axe) You are armed.
+!( Sword | Axe ) ?Sword Never shown.
+!Sword ?(Axe) >Start Swing the axe
+?(Sword | Oops
+`
+	abv, err := parser.Parse(strings.NewReader(source), "grouped.abv", false)
+	var list parser.ErrorList
+	if !errors.As(err, &list) || len(list) != 1 || list[0].Pos.Line != 8 {
+		t.Fatalf("Expected a single error on line 8, got %v", err)
+	}
+
+	cell := abv.Cells["b72c5e85"]
+	conditions := []string{}
+	for _, line := range cell.Lines[:3] {
+		conditions = append(conditions, line.Condition.String())
+	}
+	expected := "Sword | Axe,!(Sword | Axe) & Sword,!Sword & Axe"
+	if strings.Join(conditions, ",") != expected {
+		t.Errorf("Lines have wrong conditions:\nExpected %s\ngot      %s", expected, strings.Join(conditions, ","))
+	}
+	if cell.Lines[2].LinksTo != "Start" || cell.Lines[2].Text != "Swing the axe" {
+		t.Errorf("Instructions after a group were not parsed: %+v", cell.Lines[2])
+	}
+
+	scene, _ := abv.Evaluate("", inventory.State{Items: bitset.FromUint64(2)})
+	if len(scene.Paragraphs) != 2 || scene.Paragraphs[0].Text != "You are armed." {
+		t.Errorf("Holding the axe shows the wrong lines: %+v", scene.Paragraphs)
+	}
+
+	dump, err := json.Marshal(cell.Lines[0])
+	if err != nil || !strings.Contains(string(dump), `"Condition":"Sword | Axe"`) {
+		t.Errorf("Condition did not marshal as text: %s, %v", dump, err)
+	}
+}
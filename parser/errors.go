@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Pos is a position in an abventure source file. Line and Column both start at 1, and are 0 when unknown.
+type Pos struct {
+	File   string `json:",omitempty"`
+	Line   int    `json:",omitempty"`
+	Column int    `json:",omitempty"`
+}
+
+func (pos Pos) String() string {
+	out := pos.File
+	if out == "" {
+		out = "-"
+	}
+	if pos.Line > 0 {
+		out = fmt.Sprintf("%s:%d", out, pos.Line)
+		if pos.Column > 0 {
+			out = fmt.Sprintf("%s:%d", out, pos.Column)
+		}
+	}
+	return out
+}
+
+// Error is a single problem found while parsing, along with where it was found.
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (err *Error) Error() string {
+	return err.Pos.String() + ": " + err.Msg
+}
+
+// ErrorList is every problem found while parsing a file, in the order they were found.
+type ErrorList []*Error
+
+// Add appends an Error with the given position and message to the list.
+func (list *ErrorList) Add(pos Pos, msg string) {
+	*list = append(*list, &Error{Pos: pos, Msg: msg})
+}
+
+// Sort orders the list by file, line and column.
+func (list ErrorList) Sort() {
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].Pos, list[j].Pos
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+func (list ErrorList) Error() string {
+	switch len(list) {
+	case 0:
+		return "no errors"
+	case 1:
+		return list[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", list[0], len(list)-1)
+}
+
+// Err returns the list as an error, or nil if it is empty.
+func (list ErrorList) Err() error {
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}
+
+// PrintError writes every error in an ErrorList to w, one per line. Any other error is just written as-is.
+func PrintError(w io.Writer, err error) {
+	var list ErrorList
+	if errors.As(err, &list) {
+		for _, e := range list {
+			fmt.Fprintln(w, e)
+		}
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(w, err)
+	}
+}
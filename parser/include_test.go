@@ -0,0 +1,119 @@
+package parser_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/hash"
+	"github.com/demmydemon/abventure/parser"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestInclude(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.abv":           "Included\n<chapters/items.abv\n:Start\n>Cave Go to the cave\n<chapters/cave.abv\n",
+		"chapters/items.abv": "%Torch A torch.\n",
+		"chapters/cave.abv":  ":Cave\n&Torch You find a torch.\n>Start Back\n",
+	})
+
+	abv, err := parser.ParseFile(filepath.Join(dir, "main.abv"), false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	if _, ok := abv.Inventory.Items["Torch"]; !ok {
+		t.Error("Item from included file is missing")
+	}
+	cave, ok := abv.Cells[hash.Single("Cave")]
+	if !ok {
+		t.Fatal("Cell from included file is missing")
+	}
+	if cave.Pos.File != filepath.Join(dir, "chapters/cave.abv") || cave.Lines[0].Pos.Line != 2 {
+		t.Errorf("Included cell has the wrong position: %s", cave.Lines[0].Pos)
+	}
+	if len(abv.Cells[hash.PrecalcStart].Lines) != 1 {
+		t.Errorf("Start cell should end where the included file begins, got %+v", abv.Cells[hash.PrecalcStart].Lines)
+	}
+	if len(abv.Files) != 3 || abv.Files[2] != filepath.Join(dir, "chapters/cave.abv") {
+		t.Errorf("Wrong list of files read: %v", abv.Files)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.abv": "Cycles\n:Start\n<a.abv\n<missing.abv\n",
+		"a.abv":    ":A\n<b.abv\n",
+		"b.abv":    ":B\n>A >B Twice\n<a.abv\n",
+	})
+
+	_, err := parser.ParseFile(filepath.Join(dir, "main.abv"), false)
+	var list parser.ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("Expected an ErrorList, got %v", err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("Expected 3 errors, got %d: %v", len(list), list)
+	}
+	if list[0].Pos.File != filepath.Join(dir, "b.abv") || list[0].Pos.Line != 2 {
+		t.Errorf("Error in included file has the wrong position: %s", list[0])
+	}
+	if !strings.Contains(list[1].Msg, "include cycle: ") || !strings.HasSuffix(list[1].Msg, "a.abv") {
+		t.Errorf("Expected an include cycle error, got %s", list[1])
+	}
+	if list[2].Pos.Line != 4 || !strings.Contains(list[2].Msg, "missing.abv") {
+		t.Errorf("Expected an error about the missing file, got %s", list[2])
+	}
+}
+
+func TestIncludeOutside(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"game/main.abv":         "Escape\n:Start\n<3\n</etc/passwd.abv\n<../secret.abv\n<link.abv\n<chapters/one.abv\n",
+		"game/chapters/one.abv": "<../items.abv\n",
+		"game/items.abv":        "%Torch A torch.\n",
+		"secret.abv":            "The secret.\n",
+	})
+	if err := os.Symlink(filepath.Join(dir, "secret.abv"), filepath.Join(dir, "game/link.abv")); err != nil {
+		t.Skipf("Can't make a symbolic link: %s", err)
+	}
+
+	abv, err := parser.ParseFile(filepath.Join(dir, "game/main.abv"), false)
+	var list parser.ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("Expected an ErrorList, got %v", err)
+	}
+	expected := []string{
+		"include: /etc/passwd.abv must be a relative path",
+		"include: " + filepath.Join(dir, "secret.abv") + " is outside the directory of " + filepath.Join(dir, "game/main.abv"),
+		"include: " + filepath.Join(dir, "game/link.abv") + " is outside the directory of " + filepath.Join(dir, "game/main.abv"),
+	}
+	if len(list) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(list), list)
+	}
+	for i, want := range expected {
+		if list[i].Msg != want {
+			t.Errorf("Error %d: Expected %q, got %q", i, want, list[i].Msg)
+		}
+	}
+	if lines := abv.Cells[hash.PrecalcStart].Lines; len(lines) != 1 || lines[0].Text != "<3" {
+		t.Errorf("<3 should be text, got %+v", lines)
+	}
+	if _, ok := abv.Inventory.Items["Torch"]; !ok {
+		t.Error("Including a file further up, but still inside the directory, should work")
+	}
+}
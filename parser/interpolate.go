@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/demmydemon/abventure/inventory"
+)
+
+// placeholder is a part of line text in braces, replaced when the line is evaluated.
+// A plain name is replaced by the variable's value or the item's description.
+// A condition followed by ? picks between two fragments, as in `{Torch?It is bright|It is dark}`.
+type placeholder struct {
+	Source    string // As written, braces and all
+	Name      string
+	Condition Condition
+	Then      string
+	Else      string
+}
+
+// textPart is either literal text, or a placeholder.
+type textPart struct {
+	Literal     string
+	Placeholder *placeholder
+}
+
+// parseText splits line text into literal text and placeholders. Braces around anything that isn't a placeholder
+// are kept as they are, so text written before there were placeholders reads the same. A backslash before a brace
+// makes it literal.
+func parseText(text string) []textPart {
+	parts := []textPart{}
+	literal := strings.Builder{}
+	for pos := 0; pos < len(text); pos++ {
+		switch {
+		case strings.HasPrefix(text[pos:], `\{`), strings.HasPrefix(text[pos:], `\}`):
+			literal.WriteByte(text[pos+1])
+			pos++
+			continue
+		case text[pos] == '{':
+			end := strings.IndexByte(text[pos:], '}')
+			if end < 0 {
+				break
+			}
+			ph, ok := parsePlaceholder(text[pos : pos+end+1])
+			if !ok {
+				break
+			}
+			if literal.Len() > 0 {
+				parts = append(parts, textPart{Literal: literal.String()})
+				literal.Reset()
+			}
+			parts = append(parts, textPart{Placeholder: ph})
+			pos += end
+			continue
+		}
+		literal.WriteByte(text[pos])
+	}
+	if literal.Len() > 0 {
+		parts = append(parts, textPart{Literal: literal.String()})
+	}
+	return parts
+}
+
+// parsePlaceholder parses the source of a placeholder, braces and all, returning false if it isn't one.
+func parsePlaceholder(src string) (*placeholder, bool) {
+	inner := src[1 : len(src)-1]
+	condition, fragments, conditional := strings.Cut(inner, "?")
+	if !conditional {
+		name := strings.TrimSpace(inner)
+		if name == "" || strings.IndexFunc(name, func(char rune) bool { return char > 127 || !isNameChar(byte(char)) }) >= 0 {
+			return nil, false
+		}
+		return &placeholder{Source: src, Name: name}, true
+	}
+	cond, err := ParseCondition(condition)
+	if err != nil {
+		return nil, false
+	}
+	then, otherwise, _ := strings.Cut(fragments, "|")
+	return &placeholder{Source: src, Condition: cond, Then: then, Else: otherwise}, true
+}
+
+// names calls fn with every item or variable name the placeholder refers to.
+func (ph *placeholder) names(fn func(name string)) {
+	if ph.Name != "" {
+		fn(ph.Name)
+		return
+	}
+	ConditionItems(ph.Condition, fn)
+	ConditionVars(ph.Condition, fn)
+}
+
+// defined returns true if every name the placeholder refers to is an item or variable in the inventory.
+func (ph *placeholder) defined(inv *inventory.Inventory) bool {
+	defined := true
+	ph.names(func(name string) {
+		_, isItem := inv.Items[name]
+		_, isVar := inv.Vars[name]
+		defined = defined && (isItem || isVar)
+	})
+	return defined
+}
+
+func (ph *placeholder) expand(inv *inventory.Inventory) string {
+	if ph.Condition != nil {
+		if ph.Condition.Eval(inv) {
+			return ph.Then
+		}
+		return ph.Else
+	}
+	if value, isVar := inv.Value(ph.Name); isVar {
+		return strconv.Itoa(value)
+	}
+	return inv.Describe(ph.Name)
+}
+
+// Interpolate replaces every placeholder in the text with what it stands for, given the inventory.
+// Placeholders referring to items or variables that aren't defined are left as they are.
+func Interpolate(text string, inv *inventory.Inventory) string {
+	if !strings.ContainsAny(text, "{}") {
+		return text
+	}
+	out := strings.Builder{}
+	for _, part := range parseText(text) {
+		switch {
+		case part.Placeholder == nil:
+			out.WriteString(part.Literal)
+		case part.Placeholder.defined(inv):
+			out.WriteString(part.Placeholder.expand(inv))
+		default:
+			out.WriteString(part.Placeholder.Source)
+		}
+	}
+	return out.String()
+}
+
+// PlaceholderNames calls fn with every placeholder in the text, as written, and each item or variable name it refers
+// to, so they can be checked.
+func PlaceholderNames(text string, fn func(placeholder string, name string)) {
+	for _, part := range parseText(text) {
+		if ph := part.Placeholder; ph != nil {
+			ph.names(func(name string) { fn(ph.Source, name) })
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/parser"
+)
+
+func TestInterpolate(t *testing.T) {
+	inv := inventory.New()
+	inv.Define("Torch", "a flickering torch")
+	inv.DefineVar("Gold", 12, "")
+	inv.Add("Torch")
+
+	known := map[string]string{
+		"Plain text.":                             "Plain text.",
+		"You have {Gold} coins.":                  "You have 12 coins.",
+		"You hold {Torch}.":                       "You hold a flickering torch.",
+		"{Torch?Your torch flickers|It is dark}":  "Your torch flickers",
+		"{!Torch?Your torch flickers|It is dark}": "It is dark",
+		"{Gold>=20?Rich.}":                        "",
+		"{Gold < 20 & Torch?Poor, but bright.}":   "Poor, but bright.",
+		`Braces \{like this\}.`:                   "Braces {like this}.",
+		"I {love} you.":                           "I {love} you.",
+		"A {Lamp} is not defined.":                "A {Lamp} is not defined.",
+		"{Lamp?Bright.|Dark.}":                    "{Lamp?Bright.|Dark.}",
+		"Sets like {1, 2} and }{ stay.":           "Sets like {1, 2} and }{ stay.",
+		"{Torch} {{Torch}}":                       "a flickering torch {a flickering torch}",
+	}
+	for text, want := range known {
+		if got := parser.Interpolate(text, inv); got != want {
+			t.Errorf("Interpolating %q: Expected %q, got %q", text, want, got)
+		}
+	}
+}
+
+func TestLiteralBraces(t *testing.T) {
+	source := `Braces
+%Torch A torch.
+:Start
+I {love} you.
+{Torch is unclosed.
+All {Torch Lamp} here, {Gold}, and {Lamp|Torch?light}.
+`
+	abv, err := parser.Parse(strings.NewReader(source), "braces.abv", false)
+	if err != nil {
+		t.Fatalf("Text with braces that aren't placeholders should still parse, got %v", err)
+	}
+	scene, err := abv.Evaluate("", inventory.State{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"I {love} you.", "{Torch is unclosed.", "All {Torch Lamp} here, {Gold}, and {Lamp|Torch?light}."}
+	if len(scene.Paragraphs) != len(expected) {
+		t.Fatalf("Expected %d paragraphs, got %+v", len(expected), scene.Paragraphs)
+	}
+	for i, want := range expected {
+		if scene.Paragraphs[i].Text != want {
+			t.Errorf("Paragraph %d: Expected %q, got %q", i, want, scene.Paragraphs[i].Text)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SpanKind tells how a Span of text is to be shown.
+type SpanKind int
+
+const (
+	PlainSpan    SpanKind = iota
+	EmphasisSpan          // *text*
+	BoldSpan              // **text**
+	CodeSpan              // `text`
+	ImageSpan             // ![text](src), where the text describes the image
+	BreakSpan             // \n, a line break within the paragraph
+)
+
+var spanKindNames = []string{"text", "emphasis", "bold", "code", "image", "break"}
+
+func (kind SpanKind) String() string {
+	if kind < 0 || int(kind) >= len(spanKindNames) {
+		return "text"
+	}
+	return spanKindNames[kind]
+}
+
+func (kind SpanKind) MarshalText() ([]byte, error) { return []byte(kind.String()), nil }
+
+func (kind *SpanKind) UnmarshalText(text []byte) error {
+	for num, name := range spanKindNames {
+		if name == string(text) {
+			*kind = SpanKind(num)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown span kind %q", text)
+}
+
+// Span is a piece of text with the same formatting throughout.
+type Span struct {
+	Kind SpanKind
+	Text string `json:",omitempty"`
+	Src  string `json:",omitempty"` // Where the image is, for ImageSpan
+}
+
+// ParseMarkup splits text into spans of inline formatting: *emphasis*, **bold**, `code`, ![description](image.png),
+// and \n for a line break. A backslash before any of * ` ! \ makes it a plain character. Emphasis and bold must not
+// start or end with a space. Markers that are never closed are left as plain text, and formatting does not nest.
+func ParseMarkup(text string) []Span {
+	spans := []Span{}
+	plain := strings.Builder{}
+	flush := func() {
+		if plain.Len() > 0 {
+			spans = append(spans, Span{Kind: PlainSpan, Text: plain.String()})
+			plain.Reset()
+		}
+	}
+
+	for pos := 0; pos < len(text); pos++ {
+		rest := text[pos:]
+		switch {
+		case strings.HasPrefix(rest, `\n`):
+			flush()
+			spans = append(spans, Span{Kind: BreakSpan})
+			pos++
+			continue
+		case len(rest) > 1 && rest[0] == '\\' && strings.ContainsRune("*`!\\", rune(rest[1])):
+			plain.WriteByte(rest[1])
+			pos++
+			continue
+		case strings.HasPrefix(rest, "**"):
+			if end := emphasisEnd(rest, "**"); end > 0 {
+				flush()
+				spans = append(spans, Span{Kind: BoldSpan, Text: rest[2:end]})
+				pos += end + 1
+				continue
+			}
+		case rest[0] == '*':
+			if end := emphasisEnd(rest, "*"); end > 0 {
+				flush()
+				spans = append(spans, Span{Kind: EmphasisSpan, Text: rest[1:end]})
+				pos += end
+				continue
+			}
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end > 0 {
+				flush()
+				spans = append(spans, Span{Kind: CodeSpan, Text: rest[1 : 1+end]})
+				pos += end + 1
+				continue
+			}
+		case strings.HasPrefix(rest, "!["):
+			if image, size := parseImage(rest); size > 0 {
+				flush()
+				spans = append(spans, image)
+				pos += size - 1
+				continue
+			}
+		}
+		plain.WriteByte(text[pos])
+	}
+	flush()
+	return spans
+}
+
+// emphasisEnd returns where the emphasis opened by the delimiter at the start of text is closed, or 0 if it isn't.
+// Like in CommonMark, the opening delimiter must be followed by something other than a space, and the closing one must
+// follow something other than a space, so `2 * 3 * 4` is left alone.
+func emphasisEnd(text string, delim string) int {
+	if len(text) <= len(delim) || isMarkupSpace(text[len(delim)]) {
+		return 0
+	}
+	for from := len(delim) + 1; from < len(text); {
+		end := strings.Index(text[from:], delim)
+		if end < 0 {
+			return 0
+		}
+		end += from
+		if !isMarkupSpace(text[end-1]) {
+			return end
+		}
+		from = end + 1
+	}
+	return 0
+}
+
+func isMarkupSpace(char byte) bool {
+	return strings.IndexByte(spaceChars, char) >= 0
+}
+
+// parseImage parses ![description](src) at the start of text, returning the span and how much of the text it took up.
+func parseImage(text string) (Span, int) {
+	closeBracket := strings.Index(text, "](")
+	if closeBracket < 0 {
+		return Span{}, 0
+	}
+	closeParen := strings.IndexByte(text[closeBracket:], ')')
+	if closeParen < 0 {
+		return Span{}, 0
+	}
+	src := strings.TrimSpace(text[closeBracket+2 : closeBracket+closeParen])
+	if src == "" || strings.ContainsAny(src, " ") {
+		return Span{}, 0
+	}
+	return Span{Kind: ImageSpan, Text: text[2:closeBracket], Src: src}, closeBracket + closeParen + 1
+}
+
+// PlainText returns the spans as plain text, with the formatting taken out.
+// Images become their description in brackets, and line breaks become newlines.
+func PlainText(spans []Span) string {
+	out := strings.Builder{}
+	for _, span := range spans {
+		switch span.Kind {
+		case ImageSpan:
+			out.WriteString("[" + span.Text + "]")
+		case BreakSpan:
+			out.WriteString("\n")
+		default:
+			out.WriteString(span.Text)
+		}
+	}
+	return out.String()
+}
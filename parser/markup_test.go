@@ -0,0 +1,38 @@
+package parser_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/demmydemon/abventure/parser"
+)
+
+func TestParseMarkup(t *testing.T) {
+	known := map[string]string{
+		"Plain text.":                     `[{"Kind":"text","Text":"Plain text."}]`,
+		"A *small* **big** `code` thing.": `[{"Kind":"text","Text":"A "},{"Kind":"emphasis","Text":"small"},{"Kind":"text","Text":" "},{"Kind":"bold","Text":"big"},{"Kind":"text","Text":" "},{"Kind":"code","Text":"code"},{"Kind":"text","Text":" thing."}]`,
+		"Look: ![a map](map.png)":         `[{"Kind":"text","Text":"Look: "},{"Kind":"image","Text":"a map","Src":"map.png"}]`,
+		`One\nTwo`:                        `[{"Kind":"text","Text":"One"},{"Kind":"break"},{"Kind":"text","Text":"Two"}]`,
+		`Not \*emphasis\* or *unclosed`:   `[{"Kind":"text","Text":"Not *emphasis* or *unclosed"}]`,
+		"![broken](no closing paren":      `[{"Kind":"text","Text":"![broken](no closing paren"}]`,
+		"2 * 3 * 4 is 24":                 `[{"Kind":"text","Text":"2 * 3 * 4 is 24"}]`,
+		"2 ** 3 ** 2":                     `[{"Kind":"text","Text":"2 ** 3 ** 2"}]`,
+		"A stray * here.":                 `[{"Kind":"text","Text":"A stray * here."}]`,
+		"*not * this* one":                `[{"Kind":"emphasis","Text":"not * this"},{"Kind":"text","Text":" one"}]`,
+		"**":                              `[{"Kind":"text","Text":"**"}]`,
+	}
+	for text, want := range known {
+		got, err := json.Marshal(parser.ParseMarkup(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("Markup of %q parsed wrong:\nExpected %s\n     got %s", text, want, got)
+		}
+	}
+
+	plain := parser.PlainText(parser.ParseMarkup(`A *small* ![map](map.png)\nthing.`))
+	if plain != "A small [map]\nthing." {
+		t.Errorf("PlainText took out the formatting wrong: %q", plain)
+	}
+}
@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Metadata describes an abventure, for listing it. It comes from an optional block right after the title, between
+// two lines of ---, with one field: value per line.
+type Metadata struct {
+	Author      string   `json:",omitempty"`
+	Description string   `json:",omitempty"`
+	Version     string   `json:",omitempty"` // As the author likes to number them, unrelated to published versions
+	Tags        []string `json:",omitempty"`
+	Warnings    []string `json:",omitempty"` // Content warnings
+	Language    string   `json:",omitempty"`
+	Cover       string   `json:",omitempty"` // Where the cover image is
+}
+
+// metadataState tells where the parser is in relation to the metadata block.
+type metadataState int
+
+const (
+	metadataExpected metadataState = iota // The block may start on the next line after the title
+	metadataInside
+	metadataDone
+)
+
+const metadataFence = "---"
+
+// parseMetadata handles a line after the title as part of the metadata block if there is one, returning true if it
+// was. The first line that isn't part of the block is left for ParseLine.
+func (state *ParserState) parseMetadata(line string, column int) bool {
+	switch state.metadata {
+	case metadataExpected:
+		if line != metadataFence {
+			state.metadata = metadataDone
+			return false
+		}
+		state.bark("Metadata starts")
+		state.metadata = metadataInside
+		state.metadataPos = state.pos(column)
+		return true
+	case metadataInside:
+		if line == metadataFence {
+			state.bark("Metadata ends")
+			state.metadata = metadataDone
+			return true
+		}
+		state.metadataField(line, column)
+		return true
+	}
+	return false
+}
+
+// metadataField sets the metadata field given on the line. Tags and warnings are lists separated by commas, and
+// may be given more than once. Other fields may only be given once.
+func (state *ParserState) metadataField(line string, column int) {
+	name, value, found := strings.Cut(line, ":")
+	if !found || strings.TrimSpace(name) == "" {
+		state.errorf(column, "metadata should be given as field: value, but found %q", line)
+		return
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	value = strings.TrimSpace(value)
+	meta := &state.Abventure.Metadata
+	state.bark("Metadata %s: %s", name, value)
+
+	lists := map[string]*[]string{
+		"tags":     &meta.Tags,
+		"warnings": &meta.Warnings,
+	}
+	if list, ok := lists[name]; ok {
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				*list = append(*list, entry)
+			}
+		}
+		return
+	}
+
+	fields := map[string]*string{
+		"author":      &meta.Author,
+		"description": &meta.Description,
+		"version":     &meta.Version,
+		"language":    &meta.Language,
+		"cover":       &meta.Cover,
+	}
+	field, ok := fields[name]
+	if !ok {
+		state.errorf(column, "unknown metadata field %q", name)
+		return
+	}
+	if *field != "" {
+		state.errorf(column, "metadata field %s given twice", name)
+		return
+	}
+	*field = value
+}
+
+// closeMetadata reports a metadata block that goes on to the end of the file.
+func (state *ParserState) closeMetadata() {
+	if state.metadata == metadataInside {
+		state.Errors.Add(state.metadataPos, "metadata is never closed with "+metadataFence)
+	}
+}
+
+// ReadHeader reads only the title and metadata of an abventure, which is a lot quicker than parsing all of it.
+// Problems with the metadata are returned as an ErrorList, along with what could be read.
+func ReadHeader(r io.Reader, filename string) (string, Metadata, error) {
+	state := NewParserState(false)
+	state.headerOnly = true
+	if err := state.parseReader(r, filename); err != nil {
+		return state.Abventure.Title, state.Abventure.Metadata, err
+	}
+	state.closeMetadata()
+	return state.Abventure.Title, state.Abventure.Metadata, state.Errors.Err()
+}
+
+// ReadHeaderFile reads the title and metadata of the named abventure file.
+func ReadHeaderFile(filename string) (string, Metadata, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", Metadata{}, fmt.Errorf("load abventure: %w", err)
+	}
+	defer file.Close()
+	return ReadHeader(file, filename)
+}
@@ -0,0 +1,83 @@
+package parser_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/parser"
+)
+
+const withMetadata = `The Cave
+---
+author: Someone
+Description: A short walk into a cave, \
+    and back out again.
+version: 1.2
+tags: short, caves
+tags: fantasy
+warnings: darkness
+language: en
+cover: cave.png
+---
+:Start Outside
+A cave.
+`
+
+func TestMetadata(t *testing.T) {
+	abv, err := parser.Parse(strings.NewReader(withMetadata), "meta.abv", false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	expected := parser.Metadata{
+		Author:      "Someone",
+		Description: "A short walk into a cave, and back out again.",
+		Version:     "1.2",
+		Tags:        []string{"short", "caves", "fantasy"},
+		Warnings:    []string{"darkness"},
+		Language:    "en",
+		Cover:       "cave.png",
+	}
+	if abv.Title != "The Cave" || !reflect.DeepEqual(abv.Metadata, expected) {
+		t.Errorf("Wrong title or metadata: %q, %+v", abv.Title, abv.Metadata)
+	}
+	if len(abv.Cells) != 1 {
+		t.Errorf("Metadata got in the way of the cells: %+v", abv.Cells)
+	}
+
+	title, meta, err := parser.ReadHeader(strings.NewReader(withMetadata+"<missing.abv\n"), "meta.abv")
+	if err != nil {
+		t.Fatalf("Unexpected error reading header: %s", err)
+	}
+	if title != "The Cave" || !reflect.DeepEqual(meta, expected) {
+		t.Errorf("Header read wrong: %q, %+v", title, meta)
+	}
+
+	title, meta, err = parser.ReadHeader(strings.NewReader("Plain\n:Start\n---\n"), "plain.abv")
+	if err != nil || title != "Plain" || !reflect.DeepEqual(meta, parser.Metadata{}) {
+		t.Errorf("Abventure without metadata read wrong: %q, %+v, %v", title, meta, err)
+	}
+}
+
+func TestMetadataErrors(t *testing.T) {
+	_, err := parser.Parse(strings.NewReader("Broken\n---\nauthor: A\nauthor: B\nrating: 5\nno colon\n:Start\n"), "broken.abv", false)
+	var list parser.ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("Expected an ErrorList, got %v", err)
+	}
+	expected := []string{
+		"broken.abv:2:1: metadata is never closed with ---",
+		"broken.abv:4:1: metadata field author given twice",
+		`broken.abv:5:1: unknown metadata field "rating"`,
+		`broken.abv:6:1: metadata should be given as field: value, but found "no colon"`,
+		`broken.abv:7:1: metadata should be given as field: value, but found ":Start"`,
+	}
+	var got []string
+	for _, e := range list {
+		got = append(got, e.Error())
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Wrong errors:\nExpected:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
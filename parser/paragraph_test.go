@@ -0,0 +1,59 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/parser"
+)
+
+func TestContinuationAndBlankLines(t *testing.T) {
+	source := `Paragraphs
+%Torch A torch.
+:Start
+
+This is a long line \
+    that goes on \  # with a comment
+	and on.
+
+
+?Torch This is never shown.
+
+Last paragraph.
+>Start Again
+
+
+`
+	abv, err := parser.Parse(strings.NewReader(source), "paragraphs.abv", false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	lines := abv.Cells["b72c5e85"].Lines
+	if len(lines) != 6 {
+		t.Fatalf("Expected 6 lines, with empty lines collapsed and trimmed, got %d: %+v", len(lines), lines)
+	}
+	if lines[0].Text != "This is a long line that goes on and on." || lines[0].Pos.Line != 5 {
+		t.Errorf("Continued line joined wrong: %q at line %d", lines[0].Text, lines[0].Pos.Line)
+	}
+	if !lines[1].Blank || lines[2].Pos.Line != 10 {
+		t.Errorf("Lines after a continued line have the wrong positions: %+v", lines[1:3])
+	}
+
+	scene, _ := abv.Evaluate("", inventory.State{})
+	shown := []string{}
+	for _, para := range scene.Paragraphs {
+		switch {
+		case para.Blank:
+			shown = append(shown, "")
+		case para.Choice != nil:
+			shown = append(shown, ">"+para.Choice.Text)
+		default:
+			shown = append(shown, para.Text)
+		}
+	}
+	if got := strings.Join(shown, "|"); got != "This is a long line that goes on and on.||Last paragraph.|>Again" {
+		t.Errorf("Scene shows the wrong paragraphs: %q", got)
+	}
+}
@@ -2,10 +2,12 @@ package parser
 
 import (
 	"bufio"
-	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,18 +19,28 @@ var (
 	ReInstructionGlyph = regexp.MustCompile(`^([\:\>\%\?\!\&\@])(\w+)\s*(.*)$`)
 	ReInstructionWord  = regexp.MustCompile(`^([\:\>\%\?\!\&\@])([\w-_]+)$`)
 	ReComment          = regexp.MustCompile(`#.*$`)
+	ReInclude          = regexp.MustCompile(`^<(\S+\.abv)$`)
+	ReItemSlot         = regexp.MustCompile(`^%([\w-]+)@([0-9]+)$`)
 )
 
+const spaceChars = " \t\n\v\f\r\u0085\u00A0"
+
 func Trim(txt string) string {
-	txt = strings.Trim(txt, " \t\n\v\f\r\u0085\u00A0") // TODO: Still missing some very high value ones. See bufio.isSpace()
+	txt = strings.Trim(txt, spaceChars) // TODO: Still missing some very high value ones. See bufio.isSpace()
 	txt = ReComment.ReplaceAllString(txt, "")
 	return txt
 }
 
 type ParserState struct {
 	Abventure   Abventure
+	Errors      ErrorList
 	currentCell AbventureCell
+	currentFile string
 	currentLine int
+	including   []string // Cleaned paths of the files being read, outermost first, to catch include cycles
+	metadata    metadataState
+	metadataPos Pos  // Where the metadata block starts
+	headerOnly  bool // Stop after the title and metadata
 	Verbose     bool
 }
 
@@ -44,31 +56,157 @@ func NewParserState(verbose bool) ParserState {
 	}
 }
 
+// ParseFile reads and parses the named abventure file.
+// If the file has problems, an ErrorList of every problem found is returned along with what could be parsed.
 func ParseFile(filename string, verbose bool) (Abventure, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return Abventure{}, fmt.Errorf("load abventure: %w", err)
 	}
 	defer file.Close()
-	scanner := bufio.NewScanner(file)
-	// scanner.Split(bufio.ScanLines) // This is the default behaviour
+	return Parse(file, filename, verbose)
+}
 
+// Parse parses an abventure from r, using filename in positions and error messages.
+// Included files are found relative to the directory of filename.
+func Parse(r io.Reader, filename string, verbose bool) (Abventure, error) {
 	state := NewParserState(verbose)
+	state.Abventure.FileName = filename
 
-	for scanner.Scan() {
-		err := state.ParseLine(scanner.Text())
-		state.currentLine++
-		if err != nil {
-			return state.Abventure, err
-		}
+	if err := state.parseReader(r, filename); err != nil {
+		return state.Abventure, err
 	}
 
 	state.CloseCell() // Because we have to close the last cell
+	state.closeMetadata()
 
 	now := time.Now()
 	state.Abventure.ParseTime = &now
 
-	return state.Abventure, nil
+	state.Errors.Sort()
+	return state.Abventure, state.Errors.Err()
+}
+
+// parseReader parses every line read from r as coming from the named file, then goes back to the file it was in.
+func (state *ParserState) parseReader(r io.Reader, filename string) error {
+	state.Abventure.Files = append(state.Abventure.Files, filename)
+	state.including = append(state.including, filepath.Clean(filename))
+	outerFile, outerLine := state.currentFile, state.currentLine
+	defer func() {
+		state.including = state.including[:len(state.including)-1]
+		state.currentFile, state.currentLine = outerFile, outerLine
+	}()
+	state.currentFile = filename
+
+	scanner := bufio.NewScanner(r)
+	// scanner.Split(bufio.ScanLines) // This is the default behaviour
+
+	lineNumber := 0
+	pending, pendingLine := "", 0 // A line ending in a backslash, waiting for the rest of it
+	for scanner.Scan() {
+		if state.headerOnly && state.metadata == metadataDone {
+			return nil // The rest is of no interest, and may include other files
+		}
+		lineNumber++
+		line := scanner.Text()
+		if pendingLine > 0 {
+			line = pending + " " + strings.TrimLeft(line, spaceChars)
+		} else {
+			pendingLine = lineNumber
+		}
+		if joined, continues := continuation(line); continues {
+			pending = joined
+			continue
+		}
+		state.currentLine = pendingLine
+		state.ParseLine(line)
+		pending, pendingLine = "", 0
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read abventure %s: %w", filename, err)
+	}
+	if pendingLine > 0 { // The file ended with a backslash, so there is no more line to wait for.
+		state.currentLine = pendingLine
+		state.ParseLine(pending)
+	}
+	return nil
+}
+
+// include parses the named file as if its lines were right here, relative to the directory of the current file.
+func (state *ParserState) include(path string, column int) {
+	if filepath.IsAbs(path) {
+		state.errorf(column, "include: %s must be a relative path", path)
+		return
+	}
+	path = filepath.Join(filepath.Dir(state.currentFile), path)
+	if !state.insideRoot(path) {
+		state.errorf(column, "include: %s is outside the directory of %s", path, state.including[0])
+		return
+	}
+	for num, including := range state.including {
+		if including == filepath.Clean(path) {
+			cycle := append(state.including[num:len(state.including):len(state.including)], including)
+			state.errorf(column, "include cycle: %s", strings.Join(cycle, " -> "))
+			return
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		state.errorf(column, "include: %s", err)
+		return
+	}
+	defer file.Close()
+	state.bark("Including %s", path)
+	if err := state.parseReader(file, path); err != nil {
+		state.errorf(column, "include: %s", err)
+	}
+}
+
+// insideRoot returns true if the path is in the directory of the file being parsed, or below it, following any
+// symbolic links along the way.
+func (state *ParserState) insideRoot(path string) bool {
+	root := filepath.Dir(state.including[0])
+	if !isWithin(root, path) {
+		return false
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return true // Opening the file fails anyway
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return true
+	}
+	return isWithin(realRoot, realPath)
+}
+
+func isWithin(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// continuation checks if a line ends in a single backslash, meaning it continues on the next line.
+// If so, it returns the line without the backslash, or any comment after it.
+func continuation(line string) (string, bool) {
+	line = strings.TrimRight(ReComment.ReplaceAllString(line, ""), spaceChars)
+	if strings.HasSuffix(line, `\`) && !strings.HasSuffix(line, `\\`) {
+		return strings.TrimRight(line[:len(line)-1], spaceChars), true
+	}
+	return line, false
+}
+
+func (state *ParserState) pos(column int) Pos {
+	return Pos{
+		File:   state.currentFile,
+		Line:   state.currentLine,
+		Column: column,
+	}
+}
+
+func (state *ParserState) errorf(column int, format string, a ...any) {
+	state.Errors.Add(state.pos(column), fmt.Sprintf(format, a...))
+	state.bark("ERROR: "+format, a...)
 }
 
 func (state *ParserState) bark(format string, a ...any) {
@@ -78,25 +216,73 @@ func (state *ParserState) bark(format string, a ...any) {
 	}
 }
 
-func (state *ParserState) ParseLine(line string) error {
+// ParseLine parses a single line of an abventure file, adding any problems found to state.Errors.
+func (state *ParserState) ParseLine(line string) {
+	column := len(line) - len(strings.TrimLeft(line, spaceChars)) + 1
+	blank := strings.Trim(line, spaceChars) == ""
 	line = Trim(line)
 
+	if blank {
+		state.addBlank()
+		return
+	}
 	if line == "" {
-		state.bark("Empty line skipped over")
-		return nil
+		state.bark("Comment skipped over")
+		return
 	}
 
 	if state.Abventure.Title == "" {
 		state.bark("Title found: %s", line)
 		state.Abventure.Title = line
-		return nil
+		return
+	}
+	if state.metadata != metadataDone && state.parseMetadata(line, column) {
+		return
+	}
+	if state.headerOnly {
+		return
 	}
 
-	cellLine := AbventureLine{}
+	cellLine := AbventureLine{Pos: state.pos(column)}
 
 	words := strings.Split(line, " ")
 lineParse:
-	for i, word := range words {
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if strings.HasPrefix(word, "?(") || strings.HasPrefix(word, "!(") || ReComparison.MatchString(word) {
+			end := state.parseGroup(&cellLine, words, i, column)
+			for _, grouped := range words[i : end+1] {
+				column += len(grouped) + 1
+			}
+			i = end
+			continue
+		}
+		if found := ReInclude.FindStringSubmatch(word); found != nil && len(words) == 1 {
+			state.include(found[1], column)
+			return // Don't save this line
+		}
+		if found := ReVarDefinition.FindStringSubmatch(word); found != nil {
+			state.defineVar(found, words[i+1:], i, column)
+			return // Don't save this line
+		}
+		if found := ReVarChange.FindStringSubmatch(word); found != nil {
+			change, err := parseVarChange(found)
+			if err != nil {
+				state.errorf(column, "%s", err)
+			}
+			state.bark("Variable change: %s", change)
+			cellLine.Changes = append(cellLine.Changes, change)
+			column += len(word) + 1
+			continue
+		}
+		if found := ReItemSlot.FindStringSubmatch(word); found != nil {
+			slot, err := strconv.Atoi(found[2])
+			if err != nil {
+				slot = inventory.MaxSlot + 1 // Too big for Atoi is certainly too big for DefineAt
+			}
+			state.defineItem(found[1], slot, words[i+1:], i, column)
+			return // Don't save this line
+		}
 		found := ReInstructionWord.FindStringSubmatch(word)
 		if found == nil { // Done with instructions, apparently!
 			cellLine.Text = Trim(strings.Join(words[i:], " "))
@@ -105,34 +291,35 @@ lineParse:
 		}
 		switch found[1] {
 		case ":": // New cell
+			if i > 0 {
+				state.errorf(column, "cell definition %s must be at the start of the line", found[2])
+			}
 			state.CloseCell()
-			state.NewCell(found[2])
+			state.NewCell(found[2], column)
 			if len(words) > i {
 				state.currentCell.Label = Trim(strings.Join(words[i+1:], " "))
 			}
 			state.bark("New cell %s labeled %q", found[2], state.currentCell.Label)
-			return nil // Don't save this line
+			return // Don't save this line
 		case ">": // Destination
+			if cellLine.LinksTo != "" {
+				state.errorf(column, "more than one destination: %s and %s", cellLine.LinksTo, found[2])
+			}
 			state.bark("Destination: %s", found[2])
 			cellLine.LinksTo = found[2]
-		case "%": // Item definition¨
-			description := ""
-			if len(words) > i {
-				description = Trim(strings.Join(words[i+1:], " "))
-			}
-			state.bark("Item definition: %s: %q", found[2], description)
-			state.Abventure.Inventory.Define(found[2], description)
-			return nil // Don't save this line
+		case "%": // Item definition
+			state.defineItem(found[2], -1, words[i+1:], i, column)
+			return // Don't save this line
 		case "?": // Item check
 			state.bark("Item check: %s", found[2])
-			cellLine.RequireItems = append(cellLine.RequireItems, found[2])
+			cellLine.Condition = Both(cellLine.Condition, ItemCheck{Item: found[2]})
 		case "!": // Inverted item check
 			state.bark("Inverted item check: %s", found[2])
-			cellLine.ForbidItems = append(cellLine.ForbidItems, found[2])
+			cellLine.Condition = Both(cellLine.Condition, Not{X: ItemCheck{Item: found[2]}})
 		case "&": // Give item
 			text := ""
 			if len(words) > i {
-				text = Trim(strings.Join(words[i+1:], " "))
+				text = state.trailingText(words[i+1:], column, found[0])
 			}
 			state.bark("Give item: %s %q", found[2], text)
 			cellLine.GiveItem = found[2]
@@ -141,20 +328,119 @@ lineParse:
 		case "@": // Take item
 			text := ""
 			if len(words) > i {
-				text = Trim(strings.Join(words[i+1:], " "))
+				text = state.trailingText(words[i+1:], column, found[0])
 			}
 			state.bark("Take item: %s %q", found[2], text)
 			cellLine.TakeItem = found[2]
 			cellLine.Text = text
 			break lineParse
 		default:
-			return errors.New("unexpected glyph " + found[0])
+			state.errorf(column, "unexpected glyph %s", found[0])
 		}
+		column += len(word) + 1
+	}
+
+	if state.currentCell.Name == "" {
+		state.bark("Line is not part of any cell, ignoring it")
+		state.Abventure.Outside = append(state.Abventure.Outside, cellLine.Pos)
+		return
 	}
 
 	state.currentCell.Lines = append(state.currentCell.Lines, cellLine)
+}
 
-	return nil
+// defineItem handles an item definition like `%Map A map.` or `%Map@3 A map.`, where rest is the words after the name.
+// A slot of -1 means the item gets the lowest free slot.
+func (state *ParserState) defineItem(name string, slot int, rest []string, index int, column int) {
+	if index > 0 {
+		state.errorf(column, "item definition %s must be at the start of the line", name)
+	}
+	if _, isVar := state.Abventure.Inventory.Vars[name]; isVar {
+		state.errorf(column, "item %s is already defined as a variable", name)
+	}
+	description := Trim(strings.Join(rest, " "))
+	state.bark("Item definition: %s@%d: %q", name, slot, description)
+	if slot < 0 {
+		state.Abventure.Inventory.Define(name, description)
+		return
+	}
+	if err := state.Abventure.Inventory.DefineAt(name, slot, description); err != nil {
+		state.errorf(column, "%s", err)
+	}
+}
+
+// defineVar handles a variable definition like `$Gold=10 You have %d gold coins.`, where rest is the words after the name.
+func (state *ParserState) defineVar(found []string, rest []string, index int, column int) {
+	if index > 0 {
+		state.errorf(column, "variable definition %s must be at the start of the line", found[1])
+	}
+	if state.currentCell.Name != "" {
+		state.errorf(column, "variable definition %s must come before the first cell, not in cell %s", found[1], state.currentCell.Name)
+	}
+	if _, isItem := state.Abventure.Inventory.Items[found[1]]; isItem {
+		state.errorf(column, "variable %s is already defined as an item", found[1])
+	}
+	initial := 0
+	if found[2] != "" {
+		var err error
+		initial, err = strconv.Atoi(found[2])
+		if err != nil {
+			state.errorf(column, "variable %s: %s", found[1], err)
+		}
+	}
+	format := Trim(strings.Join(rest, " "))
+	state.bark("Variable definition: %s = %d: %q", found[1], initial, format)
+	state.Abventure.Inventory.DefineVar(found[1], initial, format)
+}
+
+// parseGroup parses a grouped condition like `?(Sword | Axe)` or a comparison like `?Gold>=5` starting at words[start].
+// Groups may span several words.
+// The condition is added to the line, and the index of the last word of the group is returned.
+func (state *ParserState) parseGroup(cellLine *AbventureLine, words []string, start int, column int) int {
+	depth := 0
+	end := start
+	for ; end < len(words); end++ {
+		depth += strings.Count(words[end], "(") - strings.Count(words[end], ")")
+		if depth <= 0 {
+			break
+		}
+	}
+	if end == len(words) {
+		end-- // Unbalanced, so the whole rest of the line goes in, and ParseCondition complains about it.
+	}
+
+	group := strings.Join(words[start:end+1], " ")
+	cond, err := ParseCondition(group[1:])
+	if err != nil {
+		state.errorf(column, "%s", err)
+		return end
+	}
+	if group[0] == '!' {
+		cond = Not{X: cond}
+	}
+	state.bark("Condition: %s", cond)
+	cellLine.Condition = Both(cellLine.Condition, cond)
+	return end
+}
+
+// addBlank adds an empty line to the current cell, to separate paragraphs.
+// Empty lines at the start of a cell, or right after another empty line, are skipped.
+func (state *ParserState) addBlank() {
+	lines := state.currentCell.Lines
+	if state.currentCell.Name == "" || len(lines) == 0 || lines[len(lines)-1].Blank {
+		state.bark("Empty line skipped over")
+		return
+	}
+	state.bark("Empty line")
+	state.currentCell.Lines = append(lines, AbventureLine{Blank: true, Pos: state.pos(1)})
+}
+
+// trailingText joins up the words following an item change, which must not contain further instructions.
+func (state *ParserState) trailingText(words []string, column int, instruction string) string {
+	if len(words) > 0 && (ReInstructionWord.MatchString(words[0]) || ReVarChange.MatchString(words[0])) {
+		state.errorf(column, "%s must not be followed by other instructions, but found %s", instruction, words[0])
+	}
+	return Trim(strings.Join(words, " "))
 }
 
 func (state *ParserState) CloseCell() {
@@ -162,14 +448,26 @@ func (state *ParserState) CloseCell() {
 	if state.currentCell.Name == "" {
 		return // Because this isn't a real cell, it's a zero value
 	}
+	if lines := state.currentCell.Lines; len(lines) > 0 && lines[len(lines)-1].Blank {
+		state.currentCell.Lines = lines[:len(lines)-1] // Empty lines at the end of a cell separate nothing
+	}
 	key := hash.Single(state.currentCell.Name)
+	if existing, ok := state.Abventure.Cells[key]; ok {
+		if existing.Name == state.currentCell.Name {
+			state.Errors.Add(state.currentCell.Pos, fmt.Sprintf("cell %s already defined at %s", existing.Name, existing.Pos))
+		} else {
+			state.Errors.Add(state.currentCell.Pos, fmt.Sprintf("cell name %s has the same hash as %s, defined at %s", state.currentCell.Name, existing.Name, existing.Pos))
+		}
+		return
+	}
 	state.Abventure.Cells[key] = state.currentCell
 }
 
-func (state *ParserState) NewCell(name string) {
+func (state *ParserState) NewCell(name string, column int) {
 	state.bark("Initializing cell: %s", name)
 	state.currentCell = AbventureCell{
 		Name:  name,
+		Pos:   state.pos(column),
 		Lines: []AbventureLine{},
 	}
 }
@@ -0,0 +1,80 @@
+package parser_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/hash"
+	"github.com/demmydemon/abventure/parser"
+)
+
+func TestPositions(t *testing.T) {
+	source := "Positions\n:Start Beginning\n\n    ?Torch >Start Loop\n"
+	abv, err := parser.Parse(strings.NewReader(source), "pos.abv", false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	cell, ok := abv.Cells[hash.PrecalcStart]
+	if !ok {
+		t.Fatal("Start cell missing")
+	}
+	if cell.Pos.String() != "pos.abv:2:1" {
+		t.Errorf("Cell has wrong position: Expected pos.abv:2:1, got %s", cell.Pos)
+	}
+	if len(cell.Lines) != 1 {
+		t.Fatalf("Expected 1 line in cell, got %d", len(cell.Lines))
+	}
+	if cell.Lines[0].Pos.String() != "pos.abv:4:5" {
+		t.Errorf("Line has wrong position: Expected pos.abv:4:5, got %s", cell.Lines[0].Pos)
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	source := `Errors
+Floating text
+:Start
+&Map >Start Take the map
+>Start >Start Twice
+?Torch :Nested
+:Start
+`
+	abv, err := parser.Parse(strings.NewReader(source), "err.abv", false)
+	var list parser.ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("Expected an ErrorList, got %v", err)
+	}
+	if len(abv.Outside) != 1 || abv.Outside[0].Line != 2 {
+		t.Errorf("Floating text should be ignored, but noted: %v", abv.Outside)
+	}
+
+	expected := []string{
+		"err.abv:4:1: &Map must not be followed by other instructions",
+		"err.abv:5:8: more than one destination",
+		"err.abv:6:8: cell definition Nested must be at the start of the line",
+		"err.abv:7:1: cell Start already defined at err.abv:3:1",
+	}
+	if len(list) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(list), list)
+	}
+	for i, want := range expected {
+		if !strings.HasPrefix(list[i].Error(), want) {
+			t.Errorf("Error %d: Expected %q, got %q", i, want, list[i])
+		}
+	}
+}
+
+func TestItemSlots(t *testing.T) {
+	source := "Slots\n%Map@2 A map.\n%Torch A torch.\n%Compass@2 A compass.\n:Start\n"
+	abv, err := parser.Parse(strings.NewReader(source), "slots.abv", false)
+	var list parser.ErrorList
+	if !errors.As(err, &list) || len(list) != 1 || list[0].Pos.Line != 4 {
+		t.Fatalf("Expected a single error about the slot taken twice on line 4, got %v", err)
+	}
+	if item := abv.Inventory.Items["Map"]; item.Slot != 2 || !item.Fixed || item.Description != "A map." {
+		t.Errorf("Item with explicit slot defined wrong: %+v", item)
+	}
+	if item := abv.Inventory.Items["Torch"]; item.Slot != 0 {
+		t.Errorf("Item without a slot should get the lowest free one, got %d", item.Slot)
+	}
+}
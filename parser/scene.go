@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/demmydemon/abventure/hash"
+	"github.com/demmydemon/abventure/inventory"
+)
+
+// Choice is a link out of a cell, as seen by a player.
+type Choice struct {
+	Target string          // Hash of the cell linked to
+	Cell   string          // Name of the cell linked to
+	Text   string          // What the link says
+	Spans  []Span          `json:",omitempty"` // The text, split up by inline formatting
+	State  inventory.State `json:"-"`          // The inventory state the player brings along
+	Broken bool            `json:",omitempty"`
+}
+
+// Paragraph is a single visible line of a cell. It is either text, a Choice, or Blank.
+type Paragraph struct {
+	Text   string  `json:",omitempty"`
+	Spans  []Span  `json:",omitempty"` // The text, split up by inline formatting
+	Choice *Choice `json:",omitempty"`
+	Blank  bool    `json:",omitempty"` // An empty line between paragraphs
+}
+
+// Scene is a cell evaluated for a given inventory state, holding everything a player gets to see.
+type Scene struct {
+	Hash       string
+	Name       string
+	Label      string
+	Paragraphs []Paragraph
+	Inventory  []string
+	State      inventory.State `json:"-"` // The inventory state after evaluating every line
+	Fired      []int           `json:"-"` // Indexes of the cell's lines that passed their checks, visible or not
+}
+
+// Choices returns every Choice in the scene, in the order they appear.
+func (scene *Scene) Choices() []*Choice {
+	choices := []*Choice{}
+	for _, para := range scene.Paragraphs {
+		if para.Choice != nil {
+			choices = append(choices, para.Choice)
+		}
+	}
+	return choices
+}
+
+// Evaluate runs the line against the inventory, changing it as needed.
+// It returns what the player gets to see, and false if the line is not visible at all.
+func (line *AbventureLine) Evaluate(abv *Abventure, inv *inventory.Inventory) (Paragraph, bool) {
+	para, visible, _ := line.evaluate(abv, inv)
+	return para, visible
+}
+
+// evaluate does the work of Evaluate, additionally returning if the line passed its checks at all.
+func (line *AbventureLine) evaluate(abv *Abventure, inv *inventory.Inventory) (Paragraph, bool, bool) {
+	if line.Blank {
+		return Paragraph{Blank: true}, true, true
+	}
+	if line.Condition != nil && !line.Condition.Eval(inv) {
+		return Paragraph{}, false, false // Item checks don't pass
+	}
+	if line.GiveItem != "" && !inv.Add(line.GiveItem) {
+		return Paragraph{}, false, false // Failed to give the item, so we already have it
+	}
+	if line.TakeItem != "" && !inv.Remove(line.TakeItem) {
+		return Paragraph{}, false, false // Faled to take item, so we didn't have it
+	}
+	for _, change := range line.Changes {
+		change.Apply(inv)
+	}
+	text := Interpolate(line.Text, inv)
+	if line.LinksTo == "" {
+		return Paragraph{Text: text, Spans: ParseMarkup(text)}, text != "", true
+	}
+
+	choice := Choice{
+		Target: hash.Single(line.LinksTo),
+		Cell:   line.LinksTo,
+		Text:   text,
+		State:  inv.GetState(),
+	}
+	targetCell, exists := abv.Cells[choice.Target]
+	if !exists {
+		choice.Broken = true
+		if choice.Text == "" {
+			choice.Text = "[[BROKEN LINK]]"
+		}
+	}
+	if choice.Text == "" {
+		choice.Text = targetCell.Name
+		if targetCell.Label != "" {
+			choice.Text = targetCell.Label
+		}
+	}
+	choice.Spans = ParseMarkup(choice.Text)
+	return Paragraph{Choice: &choice}, true, true
+}
+
+// Evaluate works out what a player holding the given inventory state sees in the given cell.
+// An empty cell hash means the Start cell.
+func (abv *Abventure) Evaluate(cellHash string, state inventory.State) (*Scene, error) {
+	if cellHash == "" {
+		cellHash = hash.PrecalcStart
+	}
+	cell, ok := abv.Cells[cellHash]
+	if !ok {
+		return nil, fmt.Errorf("no such cell %s", cellHash)
+	}
+
+	inv := inventory.FromExisting(abv.Inventory)
+	inv.SetState(state)
+
+	scene := Scene{
+		Hash:       cellHash,
+		Name:       cell.Name,
+		Label:      cell.Label,
+		Paragraphs: []Paragraph{},
+	}
+	for num, line := range cell.Lines {
+		para, visible, fired := line.evaluate(abv, inv)
+		if fired {
+			scene.Fired = append(scene.Fired, num)
+		}
+		if !visible {
+			continue
+		}
+		if para.Blank && (len(scene.Paragraphs) == 0 || scene.Paragraphs[len(scene.Paragraphs)-1].Blank) {
+			continue // Lines that aren't shown can leave empty lines at the start, or next to each other
+		}
+		scene.Paragraphs = append(scene.Paragraphs, para)
+	}
+	if last := len(scene.Paragraphs) - 1; last >= 0 && scene.Paragraphs[last].Blank {
+		scene.Paragraphs = scene.Paragraphs[:last]
+	}
+	scene.Inventory = inv.Contents()
+	scene.State = inv.GetState()
+
+	return &scene, nil
+}
+
+// Title returns the cell's label, or its name if it has no label.
+func (scene *Scene) Title() string {
+	if scene.Label != "" {
+		return scene.Label
+	}
+	return scene.Name
+}
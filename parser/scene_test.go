@@ -0,0 +1,70 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/bitset"
+	"github.com/demmydemon/abventure/hash"
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/parser"
+)
+
+func TestEvaluate(t *testing.T) {
+	source := `Scene
+%Torch A torch.
+:Start Beginning
+>Dark Before the torch
+&Torch You find a torch.
+?Torch After the torch
+!Torch Without the torch
+>Dark
+>Nowhere
+:Dark In the dark
+`
+	abv, err := parser.Parse(strings.NewReader(source), "scene.abv", false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	scene, err := abv.Evaluate("", inventory.State{})
+	if err != nil {
+		t.Fatalf("Unexpected evaluation error: %s", err)
+	}
+
+	if scene.Title() != "Beginning" {
+		t.Errorf("Scene has wrong title: Expected Beginning, got %q", scene.Title())
+	}
+	texts := []string{}
+	for _, para := range scene.Paragraphs {
+		if para.Choice != nil {
+			texts = append(texts, ">"+para.Choice.Text)
+			continue
+		}
+		texts = append(texts, para.Text)
+	}
+	expected := ">Before the torch|You find a torch.|After the torch|>In the dark|>[[BROKEN LINK]]"
+	if strings.Join(texts, "|") != expected {
+		t.Errorf("Scene has wrong paragraphs:\nExpected %s\ngot      %s", expected, strings.Join(texts, "|"))
+	}
+
+	choices := scene.Choices()
+	if len(choices) != 3 {
+		t.Fatalf("Expected 3 choices, got %d", len(choices))
+	}
+	if choices[0].Target != hash.Single("Dark") || !choices[0].State.Items.IsEmpty() {
+		t.Errorf("First choice should lead to Dark with an empty inventory, got %s with %v", choices[0].Target, choices[0].State.Items.Bits())
+	}
+	if !choices[1].State.Items.Equal(bitset.FromUint64(1)) {
+		t.Errorf("Second choice should carry the torch, got %v", choices[1].State.Items.Bits())
+	}
+	if !choices[2].Broken {
+		t.Error("Link to an undefined cell was not marked broken")
+	}
+	if len(scene.Inventory) != 1 || scene.Inventory[0] != "A torch." {
+		t.Errorf("Scene has wrong inventory: %v", scene.Inventory)
+	}
+
+	if _, err := abv.Evaluate("00000000", inventory.State{}); err == nil {
+		t.Error("Evaluating a missing cell did not return an error")
+	}
+}
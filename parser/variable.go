@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/demmydemon/abventure/inventory"
+)
+
+var (
+	ReVarDefinition = regexp.MustCompile(`^\$([A-Za-z][\w-]*)(?:=(-?[0-9]+))?$`)
+	ReVarChange     = regexp.MustCompile(`^~([\w-]+?)([-+=])(-?[0-9]+)$`)
+	ReComparison    = regexp.MustCompile(`^[?!][\w-]+(==|!=|<=|>=|<|>)-?[0-9]+$`)
+)
+
+// VarChange is an instruction that changes a variable, such as `~Gold+5`.
+type VarChange struct {
+	Var    string
+	Op     string // One of + - =
+	Amount int
+}
+
+// Apply makes the change to the inventory, returning false if the variable isn't defined.
+func (change VarChange) Apply(inv *inventory.Inventory) bool {
+	switch change.Op {
+	case "+":
+		return inv.AddValue(change.Var, change.Amount)
+	case "-":
+		return inv.AddValue(change.Var, -change.Amount)
+	}
+	return inv.SetValue(change.Var, change.Amount)
+}
+
+func (change VarChange) String() string {
+	return change.Var + change.Op + strconv.Itoa(change.Amount)
+}
+
+func (change VarChange) MarshalText() ([]byte, error) { return []byte(change.String()), nil }
+
+// Compare holds if the named variable compares to the value as given, such as `Gold>=5`.
+type Compare struct {
+	Var   string
+	Op    string // One of == != < <= > >=
+	Value int
+}
+
+func (cmp Compare) Eval(inv *inventory.Inventory) bool {
+	value, ok := inv.Value(cmp.Var)
+	if !ok {
+		return false // Undefined variables don't compare to anything
+	}
+	switch cmp.Op {
+	case "==":
+		return value == cmp.Value
+	case "!=":
+		return value != cmp.Value
+	case "<":
+		return value < cmp.Value
+	case "<=":
+		return value <= cmp.Value
+	case ">":
+		return value > cmp.Value
+	case ">=":
+		return value >= cmp.Value
+	}
+	return false
+}
+
+func (cmp Compare) String() string {
+	return cmp.Var + cmp.Op + strconv.Itoa(cmp.Value)
+}
+
+func (cmp Compare) MarshalText() ([]byte, error) { return []byte(cmp.String()), nil }
+
+// parseVarChange turns a matched ReVarChange into a VarChange.
+func parseVarChange(found []string) (VarChange, error) {
+	amount, err := strconv.Atoi(found[3])
+	if err != nil {
+		return VarChange{}, fmt.Errorf("variable change %s: %w", found[0], err)
+	}
+	return VarChange{Var: found[1], Op: found[2], Amount: amount}, nil
+}
@@ -0,0 +1,84 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/parser"
+)
+
+func TestVariableLines(t *testing.T) {
+	source := `Shop
+%Sword A sword.
+$Gold=10 You have %d gold coins.
+$Visits
+:Start
+~Visits+1
+?Visits>1 Welcome back.
+?(Gold>=8 & !Sword) ~Gold-8 &Sword You buy a sword.
+!Gold>=8 You can't afford anything.
+?Visits Someone has been here.
+>Start Look again
+`
+	abv, err := parser.Parse(strings.NewReader(source), "shop.abv", false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	scene, _ := abv.Evaluate("", inventory.State{})
+	if texts := sceneTexts(scene); texts != "You buy a sword.|You can't afford anything.|Someone has been here." {
+		t.Errorf("First visit shows the wrong lines: %q", texts)
+	}
+	if contents := strings.Join(scene.Inventory, "|"); contents != "A sword.|You have 2 gold coins." {
+		t.Errorf("First visit has the wrong inventory: %q", contents)
+	}
+
+	scene, _ = abv.Evaluate("", scene.Choices()[0].State)
+	if texts := sceneTexts(scene); texts != "Welcome back.|You can't afford anything.|Someone has been here." {
+		t.Errorf("Second visit shows the wrong lines: %q", texts)
+	}
+}
+
+func TestVariableErrors(t *testing.T) {
+	source := `Bad
+%Gold Some gold.
+$Gold=5
+:Start
+~Gold+2 More gold.
+`
+	_, err := parser.Parse(strings.NewReader(source), "bad.abv", false)
+	if err == nil || !strings.Contains(err.Error(), "already defined as an item") {
+		t.Errorf("Expected an error about Gold being both, got %v", err)
+	}
+}
+
+func TestVariableLookalikes(t *testing.T) {
+	source := `Prices
+$Gold=3
+:Start
+$5 is the price.
+$Price is ten coins.
+`
+	abv, err := parser.Parse(strings.NewReader(source), "prices.abv", false)
+	if err == nil || err.Error() != "prices.abv:5:1: variable definition Price must come before the first cell, not in cell Start" {
+		t.Errorf("Expected only an error about defining Price in a cell, got %v", err)
+	}
+	if _, isVar := abv.Inventory.Vars["5"]; isVar {
+		t.Error("$5 should be text, not a variable")
+	}
+	scene, _ := abv.Evaluate("", inventory.State{})
+	if texts := sceneTexts(scene); texts != "$5 is the price." {
+		t.Errorf("Wrong text: %q", texts)
+	}
+}
+
+func sceneTexts(scene *parser.Scene) string {
+	texts := []string{}
+	for _, para := range scene.Paragraphs {
+		if para.Choice == nil {
+			texts = append(texts, para.Text)
+		}
+	}
+	return strings.Join(texts, "|")
+}
@@ -0,0 +1,68 @@
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/parser"
+)
+
+// HTML renders scenes as an article followed by the inventory list, to be put inside a page.
+// Everything written by the author is escaped, and inline formatting is turned into the matching tags.
+type HTML struct {
+	Link LinkFunc // Builds the href of each choice. If nil, RelativeLink is used.
+}
+
+var htmlScene = template.Must(template.New("scene").Funcs(template.FuncMap{"markup": parser.ParseMarkup}).Parse(
+	`<article>
+    <h2>{{.Title}}</h2>
+{{range .Paragraphs}}{{if .Choice}}    <p><a{{if .Choice.Broken}} class="broken"{{end}} href="{{.Href}}">{{template "spans" .Choice.Spans}}</a></p>
+{{else if .Blank}}    <p class="blank"></p>
+{{else}}    <p>{{template "spans" .Spans}}</p>
+{{end}}{{end}}</article>
+<ul id="inventory">
+{{range .Inventory}}  <li>{{template "spans" markup .}}</li>
+{{end}}</ul>
+{{define "spans"}}{{range . -}}
+{{if eq .Kind.String "emphasis"}}<em>{{.Text}}</em>
+{{- else if eq .Kind.String "bold"}}<strong>{{.Text}}</strong>
+{{- else if eq .Kind.String "code"}}<code>{{.Text}}</code>
+{{- else if eq .Kind.String "image"}}<img src="{{.Src}}" alt="{{.Text}}">
+{{- else if eq .Kind.String "break"}}<br>
+{{- else}}{{.Text}}{{end}}{{end}}{{end}}`))
+
+type htmlParagraph struct {
+	parser.Paragraph
+	Href string
+}
+
+func (r HTML) Render(w io.Writer, scene *parser.Scene) error {
+	link := linkOrRelative(r.Link)
+
+	// html/template drops comments, so this one is written by hand, escaped so it can't end early.
+	comment := fmt.Sprintf("%s: %q, holding %q", scene.Name, scene.Label, inventory.FormatState(scene.State))
+	_, err := fmt.Fprintf(w, "\n<!-- cell %s -->\n", template.HTMLEscapeString(comment))
+	if err != nil {
+		return fmt.Errorf("write cell comment: %w", err)
+	}
+
+	paragraphs := make([]htmlParagraph, len(scene.Paragraphs))
+	for num, para := range scene.Paragraphs {
+		paragraphs[num].Paragraph = para
+		if para.Choice != nil {
+			paragraphs[num].Href = link(para.Choice.Target, para.Choice.State)
+		}
+	}
+
+	err = htmlScene.Execute(w, map[string]any{
+		"Title":      scene.Title(),
+		"Paragraphs": paragraphs,
+		"Inventory":  scene.Inventory,
+	})
+	if err != nil {
+		return fmt.Errorf("write cell: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/parser"
+)
+
+// JSONChoice is a choice as it appears in JSON output.
+type JSONChoice struct {
+	Text   string
+	Spans  []parser.Span `json:",omitempty"` // The text, split up by inline formatting
+	Cell   string
+	Target string // Hash of the cell linked to
+	State  string // Inventory state token to bring along
+	Token  string // Play token for the cell and state, as built by JSON.Token
+	Href   string
+	Broken bool `json:",omitempty"`
+}
+
+// JSONParagraph is a paragraph as it appears in JSON output. Either Text or Choice is set, unless it is Blank.
+type JSONParagraph struct {
+	Text   string        `json:",omitempty"`
+	Spans  []parser.Span `json:",omitempty"` // The text, split up by inline formatting
+	Choice *JSONChoice   `json:",omitempty"`
+	Blank  bool          `json:",omitempty"` // An empty line between paragraphs
+}
+
+// JSONScene is a scene as it appears in JSON output.
+type JSONScene struct {
+	Cell       string
+	Hash       string
+	Title      string
+	Paragraphs []JSONParagraph
+	Choices    []JSONChoice
+	Inventory  []string
+	State      string
+}
+
+// JSON renders scenes as a single JSON object.
+type JSON struct {
+	Link   LinkFunc // Builds the Href of each choice. If nil, RelativeLink is used.
+	Token  LinkFunc // Builds the Token of each choice. If nil, it's the cell hash followed by the state token.
+	Indent string   // If set, the output is indented with it.
+}
+
+// Scene converts a scene into the form used for JSON output.
+func (r JSON) Scene(scene *parser.Scene) JSONScene {
+	link := linkOrRelative(r.Link)
+	token := r.Token
+	if token == nil {
+		token = func(cellHash string, state inventory.State) string {
+			return cellHash + inventory.FormatState(state)
+		}
+	}
+
+	out := JSONScene{
+		Cell:       scene.Name,
+		Hash:       scene.Hash,
+		Title:      scene.Title(),
+		Paragraphs: make([]JSONParagraph, 0, len(scene.Paragraphs)),
+		Choices:    []JSONChoice{},
+		Inventory:  scene.Inventory,
+		State:      inventory.FormatState(scene.State),
+	}
+	if out.Inventory == nil {
+		out.Inventory = []string{}
+	}
+	for _, para := range scene.Paragraphs {
+		if para.Choice == nil {
+			out.Paragraphs = append(out.Paragraphs, JSONParagraph{Text: para.Text, Spans: para.Spans, Blank: para.Blank})
+			continue
+		}
+		choice := JSONChoice{
+			Text:   para.Choice.Text,
+			Spans:  para.Choice.Spans,
+			Cell:   para.Choice.Cell,
+			Target: para.Choice.Target,
+			State:  inventory.FormatState(para.Choice.State),
+			Token:  token(para.Choice.Target, para.Choice.State),
+			Href:   link(para.Choice.Target, para.Choice.State),
+			Broken: para.Choice.Broken,
+		}
+		out.Paragraphs = append(out.Paragraphs, JSONParagraph{Choice: &choice})
+		out.Choices = append(out.Choices, choice)
+	}
+	return out
+}
+
+func (r JSON) Render(w io.Writer, scene *parser.Scene) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", r.Indent)
+	if err := encoder.Encode(r.Scene(scene)); err != nil {
+		return fmt.Errorf("write scene JSON: %w", err)
+	}
+	return nil
+}
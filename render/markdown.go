@@ -0,0 +1,83 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/demmydemon/abventure/parser"
+)
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`,
+	`[`, `\[`, `]`, `\]`, `<`, `\<`, `>`, `\>`, `#`, `\#`,
+)
+
+// markdownSpans writes formatted text back out as Markdown, escaping everything else.
+func markdownSpans(spans []parser.Span) string {
+	out := strings.Builder{}
+	for _, span := range spans {
+		text := markdownEscaper.Replace(span.Text)
+		switch span.Kind {
+		case parser.EmphasisSpan:
+			out.WriteString("*" + text + "*")
+		case parser.BoldSpan:
+			out.WriteString("**" + text + "**")
+		case parser.CodeSpan:
+			out.WriteString("`" + span.Text + "`")
+		case parser.ImageSpan:
+			out.WriteString("![" + text + "](<" + span.Src + ">)")
+		case parser.BreakSpan:
+			out.WriteString("\\\n")
+		default:
+			out.WriteString(text)
+		}
+	}
+	return out.String()
+}
+
+// Markdown renders scenes as Markdown, with choices as links.
+type Markdown struct {
+	Link LinkFunc // Builds the target of each choice. If nil, RelativeLink is used.
+}
+
+func (r Markdown) Render(w io.Writer, scene *parser.Scene) error {
+	link := linkOrRelative(r.Link)
+
+	_, err := fmt.Fprintf(w, "## %s\n\n", markdownEscaper.Replace(scene.Title()))
+	if err != nil {
+		return fmt.Errorf("write cell name: %w", err)
+	}
+
+	for num, para := range scene.Paragraphs {
+		if para.Blank {
+			_, err = io.WriteString(w, "\n") // Text is already in paragraphs, but this keeps lists of choices apart
+		} else if choice := para.Choice; choice != nil {
+			text := markdownSpans(choice.Spans)
+			if choice.Broken {
+				text = "~~" + text + "~~"
+			}
+			_, err = fmt.Fprintf(w, "- [%s](<%s>)\n", text, link(choice.Target, choice.State))
+		} else {
+			_, err = fmt.Fprintf(w, "%s\n\n", markdownSpans(para.Spans))
+		}
+		if err != nil {
+			return fmt.Errorf("write cell line %d: %w", num, err)
+		}
+	}
+
+	if len(scene.Inventory) == 0 {
+		return nil
+	}
+	_, err = io.WriteString(w, "\n### Inventory\n\n")
+	if err != nil {
+		return fmt.Errorf("write inventory start: %w", err)
+	}
+	for _, itemDescription := range scene.Inventory {
+		_, err = fmt.Fprintf(w, "- %s\n", markdownSpans(parser.ParseMarkup(itemDescription)))
+		if err != nil {
+			return fmt.Errorf("write inventory item: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+// Package render turns evaluated cells into something a player can look at, be it HTML, plain text, Markdown or JSON.
+package render
+
+import (
+	"io"
+
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/parser"
+)
+
+// Renderer writes a scene to w in some format.
+type Renderer interface {
+	Render(w io.Writer, scene *parser.Scene) error
+}
+
+// LinkFunc builds the URL a link to the given cell hash should point to, for a player holding the given inventory state.
+type LinkFunc func(cellHash string, state inventory.State) string
+
+// RelativeLink is the plain LinkFunc, linking to the cell hash followed by the inventory state token.
+func RelativeLink(cellHash string, state inventory.State) string {
+	return "./" + cellHash + inventory.FormatState(state)
+}
+
+// linkOrRelative returns link, or RelativeLink if it is nil.
+func linkOrRelative(link LinkFunc) LinkFunc {
+	if link == nil {
+		return RelativeLink
+	}
+	return link
+}
@@ -0,0 +1,132 @@
+package render_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/parser"
+	"github.com/demmydemon/abventure/render"
+)
+
+const source = `Render
+%Torch A <bright> torch.
+:Start Beginning
+&Torch You find a *torch*.
+>Next Onwards
+>Nowhere
+:Next
+`
+
+func evaluate(t *testing.T) *parser.Scene {
+	t.Helper()
+	abv, err := parser.Parse(strings.NewReader(source), "render.abv", false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	scene, err := abv.Evaluate("", inventory.State{})
+	if err != nil {
+		t.Fatalf("Unexpected evaluation error: %s", err)
+	}
+	return scene
+}
+
+func renderString(t *testing.T, renderer render.Renderer) string {
+	t.Helper()
+	buf := bytes.Buffer{}
+	if err := renderer.Render(&buf, evaluate(t)); err != nil {
+		t.Fatalf("Unexpected render error: %s", err)
+	}
+	return buf.String()
+}
+
+func expectContains(t *testing.T, format string, output string, expected ...string) {
+	t.Helper()
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("%s output is missing %q:\n%s", format, want, output)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	output := renderString(t, render.HTML{})
+	expectContains(t, "HTML", output,
+		"<h2>Beginning</h2>",
+		"<p>You find a <em>torch</em>.</p>",
+		`<a href="./dd086b35.AQ">Onwards</a>`,
+		`<a class="broken" href="./`,
+		"<li>A &lt;bright&gt; torch.</li>",
+	)
+}
+
+func TestHTMLEscaping(t *testing.T) {
+	abv, err := parser.Parse(strings.NewReader(`Escaping
+:Start <script>alert("label")</script>
+Some <b>raw</b> HTML, some **bold** and *emphasis*.\nNext line.
+>Start <img src=x onerror=alert(1)>
+`+"Type `<go>` to ![a <map>](map.png) or ![evil](javascript:void) there.\n"), "escape.abv", false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	scene, _ := abv.Evaluate("", inventory.State{})
+	buf := bytes.Buffer{}
+	if err := (render.HTML{}).Render(&buf, scene); err != nil {
+		t.Fatalf("Unexpected render error: %s", err)
+	}
+	output := buf.String()
+
+	expectContains(t, "HTML", output,
+		"<h2>&lt;script&gt;alert(&#34;label&#34;)&lt;/script&gt;</h2>",
+		"Some &lt;b&gt;raw&lt;/b&gt; HTML, some <strong>bold</strong> and <em>emphasis</em>.<br>Next line.",
+		">&lt;img src=x onerror=alert(1)&gt;</a>",
+		`Type <code>&lt;go&gt;</code> to <img src="map.png" alt="a &lt;map&gt;"> or <img src="#ZgotmplZ" alt="evil"> there.`,
+	)
+	if strings.Contains(output, "<script>") || strings.Contains(output, "<img src=x") {
+		t.Errorf("HTML output lets author HTML through:\n%s", output)
+	}
+}
+
+func TestText(t *testing.T) {
+	output := renderString(t, render.Text{})
+	expectContains(t, "Text", output,
+		"## Beginning",
+		"You find a torch.",
+		"[1] Onwards",
+		"[2] [[BROKEN LINK]] (broken)",
+		"- A <bright> torch.",
+	)
+}
+
+func TestMarkdown(t *testing.T) {
+	output := renderString(t, render.Markdown{})
+	expectContains(t, "Markdown", output,
+		"## Beginning",
+		"You find a *torch*.",
+		"- [Onwards](<./dd086b35.AQ>)",
+		`- [~~\[\[BROKEN LINK\]\]~~](<./3fc0a05f.AQ>)`,
+		`- A \<bright\> torch.`,
+	)
+}
+
+func TestJSON(t *testing.T) {
+	output := renderString(t, render.JSON{})
+	scene := render.JSONScene{}
+	if err := json.Unmarshal([]byte(output), &scene); err != nil {
+		t.Fatalf("JSON output does not decode: %s", err)
+	}
+	if scene.Title != "Beginning" {
+		t.Errorf("JSON scene has wrong title: Expected Beginning, got %q", scene.Title)
+	}
+	if len(scene.Choices) != 2 {
+		t.Fatalf("JSON scene has wrong number of choices: Expected 2, got %d", len(scene.Choices))
+	}
+	if scene.Choices[0].State != ".AQ" || scene.Choices[0].Cell != "Next" {
+		t.Errorf("JSON choice has wrong target: Expected Next with .AQ, got %s with %q", scene.Choices[0].Cell, scene.Choices[0].State)
+	}
+	if !scene.Choices[1].Broken {
+		t.Error("JSON choice to undefined cell is not marked broken")
+	}
+}
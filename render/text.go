@@ -0,0 +1,53 @@
+package render
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/demmydemon/abventure/parser"
+)
+
+// Text renders scenes as plain text for terminals, numbering the choices in the order scene.Choices returns them.
+// Inline formatting is taken out, see parser.PlainText.
+type Text struct{}
+
+func (r Text) Render(w io.Writer, scene *parser.Scene) error {
+	_, err := fmt.Fprintf(w, "\n## %s\n\n", scene.Title())
+	if err != nil {
+		return fmt.Errorf("write cell name: %w", err)
+	}
+
+	number := 0
+	for num, para := range scene.Paragraphs {
+		if para.Blank {
+			_, err = fmt.Fprintln(w)
+		} else if para.Choice == nil {
+			_, err = fmt.Fprintln(w, parser.PlainText(para.Spans))
+		} else {
+			number++
+			broken := ""
+			if para.Choice.Broken {
+				broken = " (broken)"
+			}
+			_, err = fmt.Fprintf(w, "  [%d] %s%s\n", number, parser.PlainText(para.Choice.Spans), broken)
+		}
+		if err != nil {
+			return fmt.Errorf("write cell line %d: %w", num, err)
+		}
+	}
+
+	if len(scene.Inventory) == 0 {
+		return nil
+	}
+	_, err = fmt.Fprintln(w, "\nYou are holding:")
+	if err != nil {
+		return fmt.Errorf("write inventory start: %w", err)
+	}
+	for _, itemDescription := range scene.Inventory {
+		_, err = fmt.Fprintf(w, "  - %s\n", parser.PlainText(parser.ParseMarkup(itemDescription)))
+		if err != nil {
+			return fmt.Errorf("write inventory item: %w", err)
+		}
+	}
+	return nil
+}
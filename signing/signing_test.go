@@ -0,0 +1,90 @@
+package signing_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/bitset"
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/signing"
+)
+
+func TestSignVerify(t *testing.T) {
+	signer := signing.New("very secret")
+	sig := signer.Sign("example", "b72c5e85", ".Bw")
+	if sig == "" {
+		t.Fatal("Signer returned an empty signature")
+	}
+	if !signer.Verify("example", "b72c5e85", ".Bw", sig) {
+		t.Error("Signature did not verify")
+	}
+
+	tampered := map[string][3]string{
+		"abventure": {"amoeba", "b72c5e85", ".Bw"},
+		"cell":      {"example", "ed571050", ".Bw"},
+		"state":     {"example", "b72c5e85", ".Dw"},
+	}
+	for what, parts := range tampered {
+		if signer.Verify(parts[0], parts[1], parts[2], sig) {
+			t.Errorf("Signature verified even though the %s was changed", what)
+		}
+	}
+
+	if signing.New("other secret").Verify("example", "b72c5e85", ".Bw", sig) {
+		t.Error("Signature verified with a different secret")
+	}
+	if signer.Verify("example", "b72c5e85", ".Bw", "garbage!") {
+		t.Error("Garbage signature verified")
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	signer := signing.New("")
+	if signer != nil {
+		t.Fatal("Empty secret did not disable signing")
+	}
+	if signer.Sign("example", "b72c5e85", "") != "" {
+		t.Error("Disabled signer returned a signature")
+	}
+	if !signer.Verify("example", "b72c5e85", "", "") {
+		t.Error("Disabled signer rejected a link")
+	}
+}
+
+func TestToken(t *testing.T) {
+	state := inventory.State{Items: bitset.FromUint64(7), Values: []int{0, -3}}
+	for _, signer := range []*signing.Signer{nil, signing.New("secret")} {
+		for _, version := range []int{0, 1, 12} {
+			token := signer.Token("example", "b72c5e85", version, state)
+			cell, parsedVersion, parsed, err := signer.ParseToken("example", token)
+			if err != nil {
+				t.Errorf("Parsing token %q failed: %s", token, err)
+				continue
+			}
+			if cell != "b72c5e85" || parsedVersion != version || !parsed.Equal(state) {
+				t.Errorf("Token %q parsed wrong: got %s version %d with %q", token, cell, parsedVersion, inventory.FormatState(parsed))
+			}
+		}
+	}
+
+	if token := (*signing.Signer)(nil).Token("example", "b72c5e85", 3, inventory.State{}); token != "b72c5e85v3" {
+		t.Errorf("Versioned token of empty state: Expected b72c5e85v3, got %q", token)
+	}
+
+	signer := signing.New("secret")
+	if _, _, _, err := signer.ParseToken("example", "b72c5e85.Bw"); !errors.Is(err, signing.ErrBadSignature) {
+		t.Errorf("Unsigned token should fail with ErrBadSignature, got %v", err)
+	}
+	if _, _, _, err := signer.ParseToken("example", "b72c"); !errors.Is(err, signing.ErrBadToken) {
+		t.Errorf("Short token should fail with ErrBadToken, got %v", err)
+	}
+	if _, _, _, err := (*signing.Signer)(nil).ParseToken("example", "b72c5e85v.Bw"); !errors.Is(err, signing.ErrBadToken) {
+		t.Errorf("Token with empty version should fail with ErrBadToken, got %v", err)
+	}
+	// The version is covered by the signature, so it can't be swapped for another.
+	token := signer.Token("example", "b72c5e85", 2, state)
+	if _, _, _, err := signer.ParseToken("example", strings.Replace(token, "v2", "v1", 1)); !errors.Is(err, signing.ErrBadSignature) {
+		t.Errorf("Token with changed version should fail with ErrBadSignature, got %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+// Package walkthrough runs scripted playthroughs of abventures, so authors can check their stories still play out
+// the way they intended after making changes.
+//
+// A script is a text file with one step per line. Blank lines and anything after a # are ignored.
+//
+//	start                 Go back to the Start cell with an empty inventory. Every script begins like this.
+//	choose <text>         Take the choice with the given text, or the choice leading to the cell with that name.
+//	expect cell <name>    The current cell has the given name.
+//	expect text <text>    One of the visible lines contains the given text, with or without its formatting.
+//	expect choice <text>  There is a choice with the given text, or leading to the cell with that name.
+//	expect has <item>     The inventory holds the given item.
+//	expect lacks <item>   The inventory does not hold the given item.
+//	expect value <cmp>    A variable compares as given, such as Gold==10 or Turns<5.
+package walkthrough
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/demmydemon/abventure/inventory"
+	"github.com/demmydemon/abventure/parser"
+)
+
+// Step is a single line of a script.
+type Step struct {
+	Pos     parser.Pos
+	Command string // start, choose or expect
+	What    string // For expect: cell, text, choice, has, lacks or value
+	Arg     string
+}
+
+func (step Step) String() string {
+	return strings.Join(strings.Fields(step.Command+" "+step.What+" "+step.Arg), " ")
+}
+
+// Script is a parsed walkthrough script.
+type Script struct {
+	FileName string
+	Steps    []Step
+}
+
+var expectations = map[string]bool{"cell": true, "text": true, "choice": true, "has": true, "lacks": true, "value": true}
+
+// ParseFile reads and parses the named script file.
+func ParseFile(filename string) (*Script, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("load walkthrough: %w", err)
+	}
+	defer file.Close()
+	return Parse(file, filename)
+}
+
+// Parse parses a script from r, using filename in positions and error messages.
+// Problems are returned as a parser.ErrorList holding every one found.
+func Parse(r io.Reader, filename string) (*Script, error) {
+	script := Script{FileName: filename}
+	errs := parser.ErrorList{}
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := parser.Trim(scanner.Text())
+		if line == "" {
+			continue
+		}
+		pos := parser.Pos{File: filename, Line: lineNumber, Column: 1}
+
+		step := Step{Pos: pos}
+		step.Command, step.Arg, _ = strings.Cut(line, " ")
+		step.Arg = strings.TrimSpace(step.Arg)
+
+		switch step.Command {
+		case "start":
+			if step.Arg != "" {
+				errs.Add(pos, "start takes no argument")
+				continue
+			}
+		case "choose":
+			if step.Arg == "" {
+				errs.Add(pos, "choose needs the text or cell name of a choice")
+				continue
+			}
+		case "expect":
+			step.What, step.Arg, _ = strings.Cut(step.Arg, " ")
+			step.Arg = strings.TrimSpace(step.Arg)
+			if !expectations[step.What] {
+				errs.Add(pos, fmt.Sprintf("unknown expectation %q, expected cell, text, choice, has, lacks or value", step.What))
+				continue
+			}
+			if step.Arg == "" {
+				errs.Add(pos, "expect "+step.What+" needs something to expect")
+				continue
+			}
+			if step.What == "value" {
+				if _, err := parseComparison(step.Arg); err != nil {
+					errs.Add(pos, err.Error())
+					continue
+				}
+			}
+		default:
+			errs.Add(pos, fmt.Sprintf("unknown step %q, expected start, choose or expect", step.Command))
+			continue
+		}
+		script.Steps = append(script.Steps, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read walkthrough: %w", err)
+	}
+	return &script, errs.Err()
+}
+
+// Failure is where a script stopped matching the abventure.
+type Failure struct {
+	Step    Step
+	Cell    string   // Name of the cell the player was in
+	State   string   // Inventory state token at the time
+	Items   []string // Names of the items held at the time
+	Message string
+}
+
+func (failure *Failure) Error() string {
+	holding := "nothing"
+	if len(failure.Items) > 0 {
+		holding = strings.Join(failure.Items, ", ")
+	}
+	return fmt.Sprintf("%s: %s: %s (in %s holding %s, state %q)",
+		failure.Step.Pos, failure.Step, failure.Message, failure.Cell, holding, failure.State)
+}
+
+// Run plays through the abventure following the script, returning a *Failure at the first step that doesn't hold.
+func (script *Script) Run(abv *parser.Abventure) error {
+	cell, state := "", inventory.State{}
+	scene, err := abv.Evaluate(cell, state)
+	if err != nil {
+		return fmt.Errorf("%s: %w", script.FileName, err)
+	}
+
+	for _, step := range script.Steps {
+		fail := func(format string, a ...any) error {
+			return &Failure{
+				Step:    step,
+				Cell:    scene.Name,
+				State:   inventory.FormatState(scene.State),
+				Items:   abv.Inventory.Names(scene.State),
+				Message: fmt.Sprintf(format, a...),
+			}
+		}
+
+		switch step.Command {
+		case "start":
+			cell, state = "", inventory.State{}
+		case "choose":
+			choice := findChoice(scene, step.Arg)
+			if choice == nil {
+				return fail("no such choice")
+			}
+			if choice.Broken {
+				return fail("choice leads to undefined cell %s", choice.Cell)
+			}
+			cell, state = choice.Target, choice.State
+		case "expect":
+			if err := expect(abv, scene, step); err != "" {
+				return fail("%s", err)
+			}
+			continue // Nothing changed, so no need to evaluate again
+		}
+
+		scene, err = abv.Evaluate(cell, state)
+		if err != nil {
+			return fail("%s", err)
+		}
+	}
+	return nil
+}
+
+// findChoice returns the first choice in the scene with the given text, or leading to the cell with that name.
+func findChoice(scene *parser.Scene, textOrCell string) *parser.Choice {
+	for _, choice := range scene.Choices() {
+		if choice.Text == textOrCell {
+			return choice
+		}
+	}
+	for _, choice := range scene.Choices() {
+		if choice.Cell == textOrCell {
+			return choice
+		}
+	}
+	return nil
+}
+
+// expect checks a single expectation, returning what was wrong, or an empty string if it holds.
+func expect(abv *parser.Abventure, scene *parser.Scene, step Step) string {
+	switch step.What {
+	case "cell":
+		if scene.Name != step.Arg {
+			return "expected cell " + step.Arg
+		}
+	case "text":
+		for _, para := range scene.Paragraphs {
+			if para.Choice == nil && (strings.Contains(para.Text, step.Arg) || strings.Contains(parser.PlainText(para.Spans), step.Arg)) {
+				return ""
+			}
+		}
+		return "no visible line contains the text"
+	case "choice":
+		if findChoice(scene, step.Arg) == nil {
+			return "no such choice"
+		}
+	case "has", "lacks":
+		item, exists := abv.Inventory.Lookup(step.Arg)
+		if !exists {
+			return "no such item " + step.Arg
+		}
+		if holding := scene.State.Items.Has(item.Slot); holding != (step.What == "has") {
+			if holding {
+				return "item is held"
+			}
+			return "item is not held"
+		}
+	case "value":
+		cmp, err := parseComparison(step.Arg)
+		if err != nil {
+			return err.Error()
+		}
+		inv := inventory.FromExisting(abv.Inventory)
+		inv.SetState(scene.State)
+		value, exists := inv.Value(cmp.Var)
+		if !exists {
+			return "no such variable " + cmp.Var
+		}
+		if !cmp.Eval(inv) {
+			return fmt.Sprintf("%s is %d", cmp.Var, value)
+		}
+	}
+	return ""
+}
+
+// parseComparison parses the argument of expect value, which must be a single comparison like Gold>=5.
+func parseComparison(src string) (parser.Compare, error) {
+	cond, err := parser.ParseCondition(src)
+	if err != nil {
+		return parser.Compare{}, err
+	}
+	cmp, ok := cond.(parser.Compare)
+	if !ok {
+		return parser.Compare{}, fmt.Errorf("expect value needs a comparison like Gold>=5, got %s", src)
+	}
+	return cmp, nil
+}
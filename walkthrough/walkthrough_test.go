@@ -0,0 +1,64 @@
+package walkthrough_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/demmydemon/abventure/parser"
+	"github.com/demmydemon/abventure/walkthrough"
+)
+
+// TestAbventures runs the walkthroughs shipped next to the abventures, same as abv test does.
+func TestAbventures(t *testing.T) {
+	scripts, err := filepath.Glob("../abventures/*.abvtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, scriptFile := range scripts {
+		script, err := walkthrough.ParseFile(scriptFile)
+		if err != nil {
+			t.Errorf("Parsing %s failed: %s", scriptFile, err)
+			continue
+		}
+		abv, err := parser.ParseFile(strings.TrimSuffix(scriptFile, ".abvtest")+".abv", false)
+		if err != nil {
+			t.Errorf("Parsing abventure for %s failed: %s", scriptFile, err)
+			continue
+		}
+		if err := script.Run(&abv); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestFailure(t *testing.T) {
+	abv, err := parser.Parse(strings.NewReader("Fail\n%Key A key.\n:Start\n&Key\n>Door Open the door\n:Door\n"), "fail.abv", false)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	script, err := walkthrough.Parse(strings.NewReader("expect has Key\nchoose Open the door\nexpect cell Door\nexpect lacks Key\n"), "fail.abvtest")
+	if err != nil {
+		t.Fatalf("Unexpected script parse error: %s", err)
+	}
+
+	var failure *walkthrough.Failure
+	if !errors.As(script.Run(&abv), &failure) {
+		t.Fatal("Script that should fail did not return a Failure")
+	}
+	if failure.Step.Pos.Line != 4 || failure.Cell != "Door" || strings.Join(failure.Items, ",") != "Key" {
+		t.Errorf("Failure has wrong details: %s", failure)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := walkthrough.Parse(strings.NewReader("start now\nchoose\nexpect smell Fish\ndance\n"), "bad.abvtest")
+	var list parser.ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("Expected an ErrorList, got %v", err)
+	}
+	if len(list) != 4 {
+		t.Errorf("Expected 4 errors, got %d: %v", len(list), list)
+	}
+}